@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"counter"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// names of the languages in cloc reports
+var clocLanguages = map[string]string{
+	"cpp":        "C++",
+	"c":          "C",
+	"go":         "Go",
+	"java":       "Java",
+	"objc":       "Objective-C",
+	"csharp":     "C#",
+	"erlang":     "Erlang",
+	"python":     "Python",
+	"rust":       "Rust",
+	"javascript": "JavaScript",
+	"typescript": "TypeScript",
+	"pascal":     "Pascal",
+	"shell":      "Bourne Shell",
+	"ruby":       "Ruby",
+	"perl":       "Perl",
+	"lua":        "Lua",
+	"sql":        "SQL",
+	"haskell":    "Haskell",
+	"kotlin":     "Kotlin",
+	"swift":      "Swift",
+	"php":        "PHP",
+	"css":        "CSS",
+	"html":       "HTML",
+	"makefile":   "make",
+	"cmake":      "CMake",
+	"dockerfile": "Dockerfile",
+	"yaml":       "YAML",
+	"toml":       "TOML",
+}
+
+func clocLanguage(codetype string) string {
+	if name, ok := clocLanguages[codetype]; ok {
+		return name
+	}
+	return codetype
+}
+
+// clocStat is a stat in the columns of cloc, where each line is blank,
+// comment or code. cloc has no disabled lines, so the disabled lines without
+// code are counted as comment, like the comment lines without code.
+type clocStat struct {
+	files   int
+	blank   int
+	comment int
+	code    int
+}
+
+func newClocStat(files int, stat *counter.CodeStat) clocStat {
+	return clocStat{files: files, blank: stat.Blank, comment: stat.Total - stat.Blank - stat.Code, code: stat.Code}
+}
+
+func (stat *clocStat) lines() int {
+	return stat.blank + stat.comment + stat.code
+}
+
+type clocLanguageStat struct {
+	name string
+	clocStat
+}
+
+// clocReport is the report of cloc for a run, in its order: languages or
+// files by code, most first.
+type clocReport struct {
+	byFile    bool
+	elapsed   float64 // seconds
+	languages []clocLanguageStat
+	files     FileList
+	sum       clocStat
+}
+
+func newClocReport(files FileList, runConfig *RunConfig, allStats *AllStats) *clocReport {
+	report := &clocReport{
+		byFile:  runConfig.byFile,
+		elapsed: allStats.elapsed.Seconds(),
+		files:   append(FileList{}, files...),
+		sum:     newClocStat(allStats.totalFiles, &allStats.totalStat),
+	}
+
+	for _, v := range allStats.codeTypeOrder {
+		if codeTypeStat := allStats.codeTypeStats[v]; codeTypeStat.filenum > 0 {
+			report.languages = append(report.languages, clocLanguageStat{clocLanguage(v), newClocStat(codeTypeStat.filenum, &codeTypeStat.stat)})
+		}
+	}
+	sort.SliceStable(report.languages, func(i, j int) bool { return report.languages[i].code > report.languages[j].code })
+	sort.SliceStable(report.files, func(i, j int) bool { return report.files[i].stat.Code > report.files[j].stat.Code })
+	return report
+}
+
+func (report *clocReport) rates() (filesPerSecond, linesPerSecond float64) {
+	if report.elapsed <= 0 {
+		return 0, 0
+	}
+	return float64(report.sum.files) / report.elapsed, float64(report.sum.lines()) / report.elapsed
+}
+
+func (report *clocReport) WriteYaml(w io.Writer) {
+	filesPerSecond, linesPerSecond := report.rates()
+	fmt.Fprintf(w, "---\n# codecount\nheader :\n")
+	fmt.Fprintf(w, "  cloc_url           : codecount\n")
+	fmt.Fprintf(w, "  cloc_version       : %s\n", VERSION)
+	fmt.Fprintf(w, "  elapsed_seconds    : %v\n", report.elapsed)
+	fmt.Fprintf(w, "  n_files            : %d\n", report.sum.files)
+	fmt.Fprintf(w, "  n_lines            : %d\n", report.sum.lines())
+	fmt.Fprintf(w, "  files_per_second   : %v\n", filesPerSecond)
+	fmt.Fprintf(w, "  lines_per_second   : %v\n", linesPerSecond)
+
+	if report.byFile {
+		for _, v := range report.files {
+			stat := newClocStat(1, &v.stat)
+			fmt.Fprintf(w, "%s :\n  blank: %d\n  comment: %d\n  code: %d\n  language: %s\n",
+				strconv.Quote(v.fileName), stat.blank, stat.comment, stat.code, clocLanguage(v.codeType))
+		}
+	} else {
+		for _, v := range report.languages {
+			fmt.Fprintf(w, "%s :\n  nFiles: %d\n  blank: %d\n  comment: %d\n  code: %d\n", v.name, v.files, v.blank, v.comment, v.code)
+		}
+	}
+	fmt.Fprintf(w, "SUM:\n  blank: %d\n  comment: %d\n  code: %d\n  nFiles: %d\n", report.sum.blank, report.sum.comment, report.sum.code, report.sum.files)
+}
+
+func (report *clocReport) WriteCsv(w io.Writer) {
+	filesPerSecond, linesPerSecond := report.rates()
+	title := fmt.Sprintf("\"codecount v %s  T=%.2f s (%.1f files/s, %.1f lines/s)\"", VERSION, report.elapsed, filesPerSecond, linesPerSecond)
+
+	if report.byFile {
+		fmt.Fprintf(w, "language,filename,blank,comment,code,%s\n", title)
+		for _, v := range report.files {
+			stat := newClocStat(1, &v.stat)
+			fmt.Fprintf(w, "%s,%s,%d,%d,%d\n", clocLanguage(v.codeType), v.fileName, stat.blank, stat.comment, stat.code)
+		}
+		fmt.Fprintf(w, "SUM,,%d,%d,%d\n", report.sum.blank, report.sum.comment, report.sum.code)
+		return
+	}
+
+	fmt.Fprintf(w, "files,language,blank,comment,code,%s\n", title)
+	for _, v := range report.languages {
+		fmt.Fprintf(w, "%d,%s,%d,%d,%d\n", v.files, v.name, v.blank, v.comment, v.code)
+	}
+	fmt.Fprintf(w, "%d,SUM,%d,%d,%d\n", report.sum.files, report.sum.blank, report.sum.comment, report.sum.code)
+}
+
+func (report *clocReport) WriteXml(w io.Writer) {
+	filesPerSecond, linesPerSecond := report.rates()
+	fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?><results>\n<header>\n")
+	fmt.Fprintf(w, "  <cloc_url>codecount</cloc_url>\n")
+	fmt.Fprintf(w, "  <cloc_version>%s</cloc_version>\n", VERSION)
+	fmt.Fprintf(w, "  <elapsed_seconds>%v</elapsed_seconds>\n", report.elapsed)
+	fmt.Fprintf(w, "  <n_files>%d</n_files>\n", report.sum.files)
+	fmt.Fprintf(w, "  <n_lines>%d</n_lines>\n", report.sum.lines())
+	fmt.Fprintf(w, "  <files_per_second>%v</files_per_second>\n", filesPerSecond)
+	fmt.Fprintf(w, "  <lines_per_second>%v</lines_per_second>\n", linesPerSecond)
+	fmt.Fprintf(w, "</header>\n")
+
+	if report.byFile {
+		fmt.Fprintf(w, "<files>\n")
+		for _, v := range report.files {
+			stat := newClocStat(1, &v.stat)
+			fmt.Fprintf(w, "  <file name=\"%s\" blank=\"%d\" comment=\"%d\" code=\"%d\"  language=\"%s\"/>\n",
+				xmlEscape(v.fileName), stat.blank, stat.comment, stat.code, xmlEscape(clocLanguage(v.codeType)))
+		}
+		fmt.Fprintf(w, "  <total blank=\"%d\" comment=\"%d\" code=\"%d\" />\n</files>\n", report.sum.blank, report.sum.comment, report.sum.code)
+	} else {
+		fmt.Fprintf(w, "<languages>\n")
+		for _, v := range report.languages {
+			fmt.Fprintf(w, "  <language name=\"%s\" files_count=\"%d\" blank=\"%d\" comment=\"%d\" code=\"%d\" />\n",
+				xmlEscape(v.name), v.files, v.blank, v.comment, v.code)
+		}
+		fmt.Fprintf(w, "  <total sum_files=\"%d\" blank=\"%d\" comment=\"%d\" code=\"%d\" />\n</languages>\n",
+			report.sum.files, report.sum.blank, report.sum.comment, report.sum.code)
+	}
+	fmt.Fprintf(w, "</results>\n")
+}
+
+func xmlEscape(s string) string {
+	buf := bytes.Buffer{}
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// OutputCloc writes the report of the cloc format, "cloc-yaml", "cloc-csv"
+// or "cloc-xml", by language, or by file with -by-file.
+func OutputCloc(w io.Writer, format string, files FileList, runConfig *RunConfig, allStats *AllStats) {
+	report := newClocReport(files, runConfig, allStats)
+	switch format {
+	case "cloc-yaml":
+		report.WriteYaml(w)
+	case "cloc-csv":
+		report.WriteCsv(w)
+	case "cloc-xml":
+		report.WriteXml(w)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"counter"
+	//"fmt"
+	"testing"
+)
+
+func TestNewClocStat(t *testing.T) {
+	testdata := []struct {
+		stat   counter.CodeStat
+		wanted clocStat
+	}{
+		{counter.CodeStat{}, clocStat{files: 1}},
+		// "int a; // one", "", "// two", "int b;"
+		{counter.CodeStat{Total: 4, Code: 2, Comment: 2, Blank: 1}, clocStat{files: 1, blank: 1, comment: 1, code: 2}},
+		// "#if 0", "int a;", "// b", "#endif", "int c; // c" with -preprocess disabled
+		{counter.CodeStat{Total: 5, Code: 3, Comment: 1, Disabled: 2}, clocStat{files: 1, comment: 2, code: 3}},
+		// "#if 1", "int a;", "#else", "int b;", "// b", "#endif" with -else-of-if1 too
+		{counter.CodeStat{Total: 6, Code: 4, Disabled: 2}, clocStat{files: 1, comment: 2, code: 4}},
+	}
+
+	for i, v := range testdata {
+		stat := newClocStat(1, &v.stat)
+		if stat != v.wanted || stat.lines() != v.stat.Total {
+			t.Errorf("TestNewClocStat[%d] failed, stat = %+v, wanted = %+v", i, stat, v.wanted)
+		}
+	}
+}
+
+// files of the cloc tests, the c file is of 4 lines, 1 blank, 1 comment and 2
+// code lines in cloc
+var clocTestFiles = []testFile{
+	{name: "main.go", codeType: "go", content: "package main\n"},
+	{name: "lib/a.c", codeType: "c", content: "int a; // one\n\n// two\nint b;\n"},
+	{name: "lib/b.go", codeType: "go", content: "package b\n\n/* doc\n */\nfunc f() {}\n"},
+}
+
+func TestOutputCloc(t *testing.T) {
+	testdata := []struct {
+		format string
+		byFile bool
+		wanted string
+	}{
+		{"cloc-yaml", false, `---
+# codecount
+header :
+  cloc_url           : codecount
+  cloc_version       : 1.0.0
+  elapsed_seconds    : 2
+  n_files            : 3
+  n_lines            : 10
+  files_per_second   : 1.5
+  lines_per_second   : 5
+Go :
+  nFiles: 2
+  blank: 1
+  comment: 2
+  code: 3
+C :
+  nFiles: 1
+  blank: 1
+  comment: 1
+  code: 2
+SUM:
+  blank: 2
+  comment: 3
+  code: 5
+  nFiles: 3
+`},
+		{"cloc-yaml", true, `---
+# codecount
+header :
+  cloc_url           : codecount
+  cloc_version       : 1.0.0
+  elapsed_seconds    : 2
+  n_files            : 3
+  n_lines            : 10
+  files_per_second   : 1.5
+  lines_per_second   : 5
+"/src/lib/a.c" :
+  blank: 1
+  comment: 1
+  code: 2
+  language: C
+"/src/lib/b.go" :
+  blank: 1
+  comment: 2
+  code: 2
+  language: Go
+"/src/main.go" :
+  blank: 0
+  comment: 0
+  code: 1
+  language: Go
+SUM:
+  blank: 2
+  comment: 3
+  code: 5
+  nFiles: 3
+`},
+		{"cloc-csv", false, `files,language,blank,comment,code,"codecount v 1.0.0  T=2.00 s (1.5 files/s, 5.0 lines/s)"
+2,Go,1,2,3
+1,C,1,1,2
+3,SUM,2,3,5
+`},
+		{"cloc-csv", true, `language,filename,blank,comment,code,"codecount v 1.0.0  T=2.00 s (1.5 files/s, 5.0 lines/s)"
+C,/src/lib/a.c,1,1,2
+Go,/src/lib/b.go,1,2,2
+Go,/src/main.go,0,0,1
+SUM,,2,3,5
+`},
+		{"cloc-xml", false, `<?xml version="1.0" encoding="UTF-8"?><results>
+<header>
+  <cloc_url>codecount</cloc_url>
+  <cloc_version>1.0.0</cloc_version>
+  <elapsed_seconds>2</elapsed_seconds>
+  <n_files>3</n_files>
+  <n_lines>10</n_lines>
+  <files_per_second>1.5</files_per_second>
+  <lines_per_second>5</lines_per_second>
+</header>
+<languages>
+  <language name="Go" files_count="2" blank="1" comment="2" code="3" />
+  <language name="C" files_count="1" blank="1" comment="1" code="2" />
+  <total sum_files="3" blank="2" comment="3" code="5" />
+</languages>
+</results>
+`},
+		{"cloc-xml", true, `<?xml version="1.0" encoding="UTF-8"?><results>
+<header>
+  <cloc_url>codecount</cloc_url>
+  <cloc_version>1.0.0</cloc_version>
+  <elapsed_seconds>2</elapsed_seconds>
+  <n_files>3</n_files>
+  <n_lines>10</n_lines>
+  <files_per_second>1.5</files_per_second>
+  <lines_per_second>5</lines_per_second>
+</header>
+<files>
+  <file name="/src/lib/a.c" blank="1" comment="1" code="2"  language="C"/>
+  <file name="/src/lib/b.go" blank="1" comment="2" code="2"  language="Go"/>
+  <file name="/src/main.go" blank="0" comment="0" code="1"  language="Go"/>
+  <total blank="2" comment="3" code="5" />
+</files>
+</results>
+`},
+	}
+
+	for i, v := range testdata {
+		files, allStats := countTestFiles(t, "/src", clocTestFiles)
+		runConfig := &RunConfig{root: "/src", byFile: v.byFile}
+
+		buf := bytes.Buffer{}
+		OutputCloc(&buf, v.format, files, runConfig, allStats)
+		if buf.String() != v.wanted {
+			t.Errorf("TestOutputCloc[%d] failed, output = \n%s\nwanted = \n%s", i, buf.String(), v.wanted)
+		}
+	}
+}
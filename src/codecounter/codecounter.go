@@ -5,20 +5,42 @@ import (
 	"encoding/csv"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 type FileInfo struct {
 	fileName  string
 	shortName string
 	ext       string
+	index     int // order of the file in the walk
+	codeType  string
+	reason    string // why the file is of codeType, like "shebang"
+	encoding  string
+	endings   counter.LineEndings
+	generated bool
+	hash      string // hex sha256 of the content
+	skipped   string // why the file is not counted, like "binary"
+	err       string // error of a file skipped for "error"
 	stat      counter.CodeStat
 }
 
+// names of the file, full or short
+func (f *FileInfo) Name(runConfig *RunConfig) string {
+	if runConfig.showShortName {
+		return f.shortName
+	}
+	return f.fileName
+}
+
 type FileList []*FileInfo
 
 func (f FileList) GetFileNameMaxLen() (fullNameMaxLen, shortNameMaxLen int) {
@@ -34,28 +56,53 @@ func (f FileList) GetFileNameMaxLen() (fullNameMaxLen, shortNameMaxLen int) {
 	return fullNameMaxLen, shortNameMaxLen
 }
 
-func GetFiles(root string, filters []string, files *FileList) error {
+// GetFiles sends files under root matching filters, and files without
+// extension if withoutExt is set, to files in walk order. Files and
+// directories ignored by ignorer are skipped, without walking into them.
+func GetFiles(root string, filters []string, withoutExt bool, ignorer *Ignorer, files chan<- *FileInfo) error {
+	index := 0
 	walkFunc := func(path string, f os.FileInfo, err error) error {
 		if f == nil {
 			return nil
 		}
 
+		rel, _ := filepath.Rel(root, path)
+		rel = filepath.ToSlash(rel)
 		if f.IsDir() {
+			if path == root {
+				rel = ""
+			} else if ignorer.Ignored(rel, true) {
+				return filepath.SkipDir
+			}
+			if err := ignorer.EnterDir(path, rel); err != nil {
+				log.Printf("ERROR: read ignore files in %s failed: %v", path, err)
+			}
+			return nil
+		}
+
+		if ignorer.Ignored(rel, false) {
 			return nil
 		}
 
 		for _, v := range filters {
 			if ok, _ := filepath.Match(v, f.Name()); ok {
-				fileinfo := &FileInfo{fileName: path, shortName: filepath.Base(path)}
+				fileinfo := &FileInfo{fileName: path, shortName: filepath.Base(path), index: index}
 
 				ext := filepath.Ext(path)
 				if len(ext) != 0 {
 					fileinfo.ext = strings.ToLower(ext[1:])
 				}
 
-				*files = append(*files, fileinfo)
+				files <- fileinfo
+				index++
+				return nil
 			}
 		}
+
+		if withoutExt && filepath.Ext(path) == "" && !strings.HasPrefix(f.Name(), ".") {
+			files <- &FileInfo{fileName: path, shortName: filepath.Base(path), index: index}
+			index++
+		}
 		return nil
 	}
 
@@ -63,11 +110,12 @@ func GetFiles(root string, filters []string, files *FileList) error {
 }
 
 type ExtMapToCodeType struct {
-	maps map[string]string
+	maps  map[string]string
+	names map[string]string // file names without extension, like Makefile
 }
 
 func NewExtMapToCodeType() *ExtMapToCodeType {
-	return &ExtMapToCodeType{maps: make(map[string]string)}
+	return &ExtMapToCodeType{maps: make(map[string]string), names: make(map[string]string)}
 }
 
 func (c *ExtMapToCodeType) BindFiltersToCodeType(filters string, codetype string) {
@@ -77,13 +125,53 @@ func (c *ExtMapToCodeType) BindFiltersToCodeType(filters string, codetype string
 		if v == "" {
 			continue
 		}
+		if !strings.HasPrefix(v, "*.") {
+			c.names[v] = strings.ToLower(codetype)
+			continue
+		}
 		c.maps[strings.ToLower(filepath.Ext(v)[1:])] = strings.ToLower(codetype)
 	}
 }
 
+// GetCodeType returns the code type of file and the reason for it, or "" if
+// it is unknown. Well-known file names come first. With detect set, files
+// with an ambiguous or unknown extension are detected by lines, their first
+// lines: a modeline, then a shebang line, then content heuristics.
+func (c *ExtMapToCodeType) GetCodeType(file *FileInfo, detect bool, lines []string) (codetype, reason string) {
+	if codetype, ok := c.names[file.shortName]; ok {
+		return codetype, counter.DETECT_REASON_FILENAME
+	}
+
+	codetype, ok := c.maps[file.ext]
+	if ok && (!detect || !counter.IsAmbiguousExtension(file.ext)) {
+		return codetype, counter.DETECT_REASON_EXTENSION
+	}
+	if !detect {
+		return "", ""
+	}
+
+	if lang := counter.DetectModeline(lines); lang != "" {
+		return lang, counter.DETECT_REASON_MODELINE
+	}
+	if len(lines) > 0 {
+		if lang := counter.DetectShebang(lines[0]); lang != "" {
+			return lang, counter.DETECT_REASON_SHEBANG
+		}
+	}
+	if lang := counter.DetectHeuristic(file.ext, lines); lang != "" {
+		return lang, counter.DETECT_REASON_HEURISTIC
+	}
+
+	if ok {
+		return codetype, counter.DETECT_REASON_EXTENSION
+	}
+	return "", ""
+}
+
 type RunConfig struct {
 	root          string
 	filter        string
+	allLanguages  bool
 	showEachFile  bool
 	showShortName bool
 	sortStat      bool
@@ -91,30 +179,128 @@ type RunConfig struct {
 	sortReverse   bool
 	csvOutput     bool
 	csvFileName   string
-
-	exts []*string
+	preprocess    string
+	elseOfIfOne   bool
+	langDefFile   string
+	detect        bool
+	jobs          int
+	countBinary   bool
+	countMinified bool
+	format        string
+	byFile        bool
+	htmlFileName  string
+	tree          bool
+	depth         int
+	treeCsvFile   string
+	diff          bool     // the diff subcommand
+	diffArgs      []string // the roots or json reports to diff
+	excludeDir    string
+	exclude       string
+	gitignore     bool
+
+	generatedPatterns []string
+	excludeGenerated  bool
+
+	exts              []*string
+	languages         []*counter.LanguageDefinition
+	generatedDetector *counter.GeneratedDetector
 }
 
-func (runConfig *RunConfig) Parse(codeConfigs []CodeConfig) {
-	flag.StringVar(&runConfig.root, "path", ".", "path for code")
-	flag.StringVar(&runConfig.filter, "filter", "*.cpp;*.cxx;*.hpp;*.hxx;*.c++;*.cc;*.c;*.h;*.go;*.java;*.erl;*.hrl;*.yrl", "file filters")
-	flag.BoolVar(&runConfig.showEachFile, "show", false, "show each file stat")
-	flag.BoolVar(&runConfig.showShortName, "short", true, "show file name without path")
-	flag.BoolVar(&runConfig.sortStat, "sort", true, "sort stat result")
-	flag.StringVar(&runConfig.sortField, "sortfield", "code", "set sort field: fullname, shortname, total, code, comment, blank, comment-percent")
-	flag.BoolVar(&runConfig.sortReverse, "reverse", true, "sort reverse")
-	flag.BoolVar(&runConfig.csvOutput, "csv", true, "enable to output csv file")
-	flag.StringVar(&runConfig.csvFileName, "csvfile", "result.csv", "csv file name")
-
-	flag.Parse()
+// Parse parses the command line args, without the program name. Args
+// starting with "diff" are of the diff subcommand.
+func (runConfig *RunConfig) Parse(args []string, codeConfigs []CodeConfig) {
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags.StringVar(&runConfig.root, "path", ".", "path for code")
+	flags.StringVar(&runConfig.filter, "filter", "", "file filters, empty for files of all known source languages")
+	flags.BoolVar(&runConfig.allLanguages, "all-languages", false, "also count data and markup languages like html and yaml without -filter")
+	flags.BoolVar(&runConfig.showEachFile, "show", false, "show each file stat")
+	flags.BoolVar(&runConfig.showShortName, "short", true, "show file name without path")
+	flags.BoolVar(&runConfig.sortStat, "sort", true, "sort stat result")
+	flags.StringVar(&runConfig.sortField, "sortfield", "code", "set sort field: fullname, shortname, total, code, comment, doc, blank, disabled, comment-percent")
+	flags.BoolVar(&runConfig.sortReverse, "reverse", true, "sort reverse")
+	flags.StringVar(&runConfig.format, "format", "text", "output format: text, json, cloc-yaml, cloc-csv, cloc-xml")
+	flags.BoolVar(&runConfig.byFile, "by-file", false, "report each file instead of each language in cloc formats")
+	flags.StringVar(&runConfig.htmlFileName, "htmlfile", "", "html report file name, empty for none; -html sets the extensions of html files")
+	flags.BoolVar(&runConfig.tree, "tree", false, "report the stat of each directory with its subdirectories")
+	flags.IntVar(&runConfig.depth, "depth", 0, "depth of directories in -tree reports, 0 for all")
+	flags.StringVar(&runConfig.treeCsvFile, "treecsvfile", "tree.csv", "csv file name of -tree")
+	flags.BoolVar(&runConfig.csvOutput, "csv", true, "enable to output csv file")
+	flags.StringVar(&runConfig.csvFileName, "csvfile", "result.csv", "csv file name")
+	flags.StringVar(&runConfig.preprocess, "preprocess", "none", "count c/c++ lines in #if 0 as: none(code), comment, disabled")
+	flags.BoolVar(&runConfig.elseOfIfOne, "else-of-if1", false, "also count c/c++ lines in #else of #if 1 as -preprocess")
+	flags.StringVar(&runConfig.langDefFile, "langdef", "", "json file of more language definitions")
+	flags.StringVar(&runConfig.excludeDir, "exclude-dir", "", "excluded directories besides the ones of version control, names or paths from -path with ** like build/**/gen")
+	flags.StringVar(&runConfig.exclude, "exclude", "", "excluded files and directories, names or paths from -path with ** like **/*_test.go")
+	flags.BoolVar(&runConfig.gitignore, "gitignore", false, "skip files ignored by .gitignore and .ignore files")
+	flags.BoolVar(&runConfig.countBinary, "binary", false, "count binary files instead of skipping them")
+	flags.BoolVar(&runConfig.countMinified, "minified", false, "count minified files with very long lines instead of skipping them")
+	flags.Func("generated-pattern", "regexp of a banner line of generated files, may be repeated", func(expr string) error {
+		runConfig.generatedPatterns = append(runConfig.generatedPatterns, expr)
+		return nil
+	})
+	flags.BoolVar(&runConfig.excludeGenerated, "exclude-generated", false, "skip generated files instead of counting them in totals")
+	flags.IntVar(&runConfig.jobs, "jobs", runtime.GOMAXPROCS(0), "number of files counted in parallel")
+	flags.BoolVar(&runConfig.detect, "detect", true, "detect language of .h and extensionless files by modeline, shebang and content")
+
+	if len(args) > 0 && args[0] == "diff" {
+		runConfig.diff = true
+		args = args[1:]
+	}
+	flags.Parse(args)
+	runConfig.diffArgs = flags.Args()
 
 	runConfig.exts = make([]*string, 0)
 	for _, v := range codeConfigs {
-		runConfig.exts = append(runConfig.exts, flag.String(v.codeType, v.filters, v.filtersDesc))
+		runConfig.exts = append(runConfig.exts, flags.String(v.codeType, v.filters, v.filtersDesc))
 	}
 }
 
 func (runConfig *RunConfig) Check() bool {
+	if runConfig.jobs < 1 {
+		fmt.Printf("ERROR: jobs %d is invalid", runConfig.jobs)
+		return false
+	}
+
+	if runConfig.depth < 0 {
+		fmt.Printf("ERROR: depth %d is invalid", runConfig.depth)
+		return false
+	}
+
+	if _, ok := outputFormats[runConfig.format]; !ok {
+		fmt.Printf("ERROR: format \"%s\" is invalid", runConfig.format)
+		return false
+	}
+
+	if _, ok := preprocessModes[strings.ToLower(runConfig.preprocess)]; !ok {
+		fmt.Printf("ERROR: preprocess mode \"%s\" is invalid", runConfig.preprocess)
+		return false
+	}
+
+	if runConfig.langDefFile != "" {
+		languages, err := counter.LoadLanguageFile(runConfig.langDefFile)
+		if err != nil {
+			fmt.Printf("ERROR: cannot load language definitions: %v", err)
+			return false
+		}
+		runConfig.languages = languages
+	}
+
+	runConfig.generatedDetector = counter.NewGeneratedDetector()
+	for _, v := range runConfig.generatedPatterns {
+		if err := runConfig.generatedDetector.AddPattern(v); err != nil {
+			fmt.Printf("ERROR: generated pattern \"%s\" is invalid: %v", v, err)
+			return false
+		}
+	}
+
+	if runConfig.diff {
+		if len(runConfig.diffArgs) != 2 {
+			fmt.Printf("ERROR: diff needs two paths or json reports, like: codecounter diff [flags] old new")
+			return false
+		}
+		return true
+	}
+
 	_, err := os.Stat(runConfig.root)
 	if err == nil {
 		return true
@@ -128,10 +314,65 @@ func (runConfig *RunConfig) Check() bool {
 	return false
 }
 
+// metadata directories of version control systems, always excluded
+var vcsDirs = []string{".git", ".hg", ".svn", ".bzr", "_darcs", "CVS"}
+
+// NewIgnorer returns an Ignorer of -exclude-dir, -exclude and, if -gitignore
+// is set, the ignore files, for a walk of runConfig.root.
+func (runConfig *RunConfig) NewIgnorer() *Ignorer {
+	patterns := []string{}
+	for _, v := range append(vcsDirs, strings.Split(runConfig.excludeDir, ";")...) {
+		if v != "" {
+			patterns = append(patterns, strings.TrimSuffix(v, "/")+"/")
+		}
+	}
+	patterns = append(patterns, strings.Split(runConfig.exclude, ";")...)
+	excludes := []*IgnoreRules{NewIgnoreRules("", patterns)}
+
+	if runConfig.gitignore {
+		return NewIgnorer(excludes, IgnoreFileNames)
+	}
+	return NewIgnorer(excludes, nil)
+}
+
+var outputFormats = map[string]bool{
+	"text":      true,
+	"json":      true,
+	"cloc-yaml": true,
+	"cloc-csv":  true,
+	"cloc-xml":  true,
+}
+
+var preprocessModes = map[string]int{
+	"none":     counter.CPP_PREPROCESS_NONE,
+	"comment":  counter.CPP_PREPROCESS_COMMENT,
+	"disabled": counter.CPP_PREPROCESS_DISABLED,
+}
+
 type CodeConfig struct {
 	codeType    string
 	filters     string
 	filtersDesc string
+	byDefault   bool // counted without -filter, unlike data and markup languages
+}
+
+// AddLanguageConfigs adds the code configs of languages, replacing the
+// configs of the same code type.
+func AddLanguageConfigs(codeConfigs []CodeConfig, languages []*counter.LanguageDefinition) []CodeConfig {
+	for _, v := range languages {
+		config := CodeConfig{v.Name, v.Filters(), "extions for " + v.Name + " files", v.CountedByDefault()}
+		found := false
+		for i := range codeConfigs {
+			if codeConfigs[i].codeType == v.Name {
+				codeConfigs[i] = config
+				found = true
+			}
+		}
+		if !found {
+			codeConfigs = append(codeConfigs, config)
+		}
+	}
+	return codeConfigs
 }
 
 type CodeTypeStat struct {
@@ -140,15 +381,19 @@ type CodeTypeStat struct {
 }
 
 type AllStats struct {
-	totalStat     counter.CodeStat
-	codeTypeStats map[string]*CodeTypeStat
-	codeTypeOrder []string
-	maxPrefixLen  int
-	totalFiles    int
+	totalStat      counter.CodeStat
+	codeTypeStats  map[string]*CodeTypeStat
+	codeTypeOrder  []string
+	maxPrefixLen   int
+	totalFiles     int
+	skippedFiles   FileList
+	generatedStats map[string]*CodeTypeStat // generated files, counted in totals too
+	totalGenerated CodeTypeStat
+	elapsed        time.Duration // of counting the files
 }
 
 func NewAllStats() *AllStats {
-	return &AllStats{codeTypeStats: make(map[string]*CodeTypeStat)}
+	return &AllStats{codeTypeStats: make(map[string]*CodeTypeStat), generatedStats: make(map[string]*CodeTypeStat)}
 }
 
 func (allStats *AllStats) getMaxPrefixLen(files FileList, runConfig *RunConfig) {
@@ -171,7 +416,19 @@ func (allStats *AllStats) getMaxPrefixLen(files FileList, runConfig *RunConfig)
 		}
 	}
 
-	str := fmt.Sprintf("total %d files", len(files))
+	for k, v := range allStats.generatedStats {
+		str := fmt.Sprintf("total %d %s generated files", v.filenum, k)
+		if len(str) > allStats.maxPrefixLen {
+			allStats.maxPrefixLen = len(str)
+		}
+	}
+
+	str := fmt.Sprintf("total %d generated files", allStats.totalGenerated.filenum)
+	if len(str) > allStats.maxPrefixLen {
+		allStats.maxPrefixLen = len(str)
+	}
+
+	str = fmt.Sprintf("total %d files", len(files))
 	if len(str) > allStats.maxPrefixLen {
 		allStats.maxPrefixLen = len(str)
 	}
@@ -198,6 +455,42 @@ func (allStats *AllStats) AddStat(codetype string, stat *counter.CodeStat) {
 	allStats.totalFiles++
 }
 
+// AddGenerated adds the stat of a generated file to the generated totals,
+// besides AddStat.
+func (allStats *AllStats) AddGenerated(codetype string, stat *counter.CodeStat) {
+	codetypeStat, ok := allStats.generatedStats[codetype]
+	if !ok {
+		codetypeStat = &CodeTypeStat{}
+		allStats.generatedStats[codetype] = codetypeStat
+	}
+
+	codetypeStat.filenum++
+	codetypeStat.stat.Add(stat)
+
+	allStats.totalGenerated.filenum++
+	allStats.totalGenerated.stat.Add(stat)
+}
+
+func (allStats *AllStats) AddSkipped(file *FileInfo) {
+	allStats.skippedFiles = append(allStats.skippedFiles, file)
+}
+
+func (allStats *AllStats) PrintSkipped(runConfig *RunConfig) (ret string) {
+	if len(allStats.skippedFiles) == 0 {
+		return ""
+	}
+
+	ret += fmt.Sprintf("skipped %d files:\n", len(allStats.skippedFiles))
+	for _, v := range allStats.skippedFiles {
+		ret += fmt.Sprintf("  %s: %s", v.Name(runConfig), v.skipped)
+		if v.err != "" {
+			ret += fmt.Sprintf(" (%s)", v.err)
+		}
+		ret += "\n"
+	}
+	return ret
+}
+
 func (allStats *AllStats) Print() (ret string) {
 
 	for _, v := range allStats.codeTypeOrder {
@@ -210,6 +503,23 @@ func (allStats *AllStats) Print() (ret string) {
 		}
 	}
 
+	if allStats.totalGenerated.filenum > 0 {
+		for _, v := range allStats.codeTypeOrder {
+			codeTypeStat, ok := allStats.generatedStats[v]
+			if ok {
+				str := fmt.Sprintf("total %d %s generated files", codeTypeStat.filenum, v)
+				ret += fmt.Sprintf("%s:  ", str)
+				ret += PrintIdent(allStats.maxPrefixLen - len(str))
+				ret += fmt.Sprintf("%s\n", codeTypeStat.stat.String())
+			}
+		}
+
+		str := fmt.Sprintf("total %d generated files", allStats.totalGenerated.filenum)
+		ret += fmt.Sprintf("%s:  ", str)
+		ret += PrintIdent(allStats.maxPrefixLen - len(str))
+		ret += fmt.Sprintf("%s\n", allStats.totalGenerated.stat.String())
+	}
+
 	str := fmt.Sprintf("total %d files", allStats.totalFiles)
 	ret += fmt.Sprintf("%s:  ", str)
 	ret += PrintIdent(allStats.maxPrefixLen - len(str))
@@ -217,37 +527,98 @@ func (allStats *AllStats) Print() (ret string) {
 	return ret
 }
 
-func (allStats *AllStats) WriteToCsvFile(w *csv.Writer) {
+func (allStats *AllStats) WriteToCsvFile(w *csv.Writer, runConfig *RunConfig) {
 	for _, v := range allStats.codeTypeOrder {
 		codeTypeStat, _ := allStats.codeTypeStats[v]
 		if codeTypeStat.filenum > 0 {
 			line := []string{fmt.Sprintf("total %d %s files", codeTypeStat.filenum, v)}
 			line = append(line, codeTypeStat.stat.StringSlice()...)
-			w.Write(line)
+			line = append(line, v)
+			w.Write(padCsvLine(line))
 		}
 	}
 
+	if allStats.totalGenerated.filenum > 0 {
+		for _, v := range allStats.codeTypeOrder {
+			codeTypeStat, ok := allStats.generatedStats[v]
+			if ok {
+				line := []string{fmt.Sprintf("total %d %s generated files", codeTypeStat.filenum, v)}
+				line = append(line, codeTypeStat.stat.StringSlice()...)
+				line = append(line, v)
+				line = padCsvLine(line)
+				line[csvGeneratedColumn] = "true"
+				w.Write(line)
+			}
+		}
+
+		line := []string{fmt.Sprintf("total %d generated files", allStats.totalGenerated.filenum)}
+		line = append(line, allStats.totalGenerated.stat.StringSlice()...)
+		line = padCsvLine(line)
+		line[csvGeneratedColumn] = "true"
+		w.Write(line)
+	}
+
 	line := []string{fmt.Sprintf("total %d files", allStats.totalFiles)}
 	line = append(line, allStats.totalStat.StringSlice()...)
-	w.Write(line)
+	w.Write(padCsvLine(line))
+
+	for _, v := range allStats.skippedFiles {
+		w.Write(csvFileLine(v, runConfig))
+	}
 }
 
 func main() {
+	RunCommand(os.Args[1:], os.Stdout)
+}
 
+// RunCommand runs codecounter with the command line args, without the
+// program name, and writes its report to w.
+func RunCommand(args []string, w io.Writer) {
 	codeConfigs := []CodeConfig{
-		{"cpp", "*.cpp;*.cxx;*.hpp;*.hxx;*.c++;*.cc", "extions for c/c++ files"},
-		{"c", "*.c;*.h", "extions for c files"},
-		{"go", "*.go", "extions for go files"},
-		{"java", "*.java", "extions for java files"},
-		{"erlang", "*.erl;*.hrl;*.yrl", "extions for erlang files"},
+		{"cpp", "*.cpp;*.cxx;*.hpp;*.hxx;*.c++;*.cc", "extions for c/c++ files", true},
+		{"c", "*.c;*.h", "extions for c files", true},
+		{"go", "*.go", "extions for go files", true},
+		{"java", "*.java", "extions for java files", true},
+		{"objc", "*.m;*.mm", "extions for objective-c files", true},
+		{"csharp", "*.cs", "extions for c# files", true},
+		{"erlang", "*.erl;*.hrl;*.yrl;*.escript;rebar.config", "extions for erlang files", true},
+		{"python", "*.py;*.pyw;*.pyi", "extions for python files", true},
+		{"rust", "*.rs", "extions for rust files", true},
+		{"javascript", "*.js;*.mjs;*.cjs;*.jsx", "extions for javascript files", true},
+		{"typescript", "*.ts;*.mts;*.cts;*.tsx", "extions for typescript files", true},
+		{"pascal", "*.pas;*.dpr;*.pp;*.inc", "extions for pascal files", true},
 	}
+	codeConfigs = AddLanguageConfigs(codeConfigs, counter.BuiltinLanguageDefinitions())
 
 	runConfig := RunConfig{}
-	runConfig.Parse(codeConfigs)
+	runConfig.Parse(args, codeConfigs)
 	if !runConfig.Check() {
 		return
 	}
+	codeConfigs = AddLanguageConfigs(codeConfigs, runConfig.languages)
 
+	filters := []string{}
+	for _, v := range codeConfigs {
+		if v.byDefault || runConfig.allLanguages {
+			filters = append(filters, v.filters)
+		}
+	}
+	if runConfig.filter == "" {
+		runConfig.filter = strings.Join(filters, ";")
+	}
+
+	if runConfig.diff {
+		RunDiff(w, &runConfig, codeConfigs)
+		return
+	}
+
+	files, allStats := Count(&runConfig, codeConfigs)
+	OutputResult(w, files, &runConfig, allStats)
+}
+
+// Count counts the files under runConfig.root of the code types of
+// codeConfigs, and returns them with their stats.
+func Count(runConfig *RunConfig, codeConfigs []CodeConfig) (FileList, *AllStats) {
 	allStats := NewAllStats()
 	extMapToCodeType := NewExtMapToCodeType()
 	for _, v := range codeConfigs {
@@ -255,40 +626,155 @@ func main() {
 		allStats.AddCodeType(v.codeType)
 	}
 
-	files := FileList{}
-	GetFiles(runConfig.root, strings.Split(runConfig.filter, ";"), &files)
-
-	Run(files, extMapToCodeType, allStats)
-	OutputResult(files, &runConfig, allStats)
+	start := time.Now()
+	files := Run(runConfig, extMapToCodeType, allStats)
+	allStats.elapsed = time.Since(start)
+	return files, allStats
 }
 
-func Run(files FileList, extMapToCodeType *ExtMapToCodeType, allStats *AllStats) {
+// Run counts the files under runConfig.root with runConfig.jobs workers, and
+// returns the counted files in walk order whatever the number of workers.
+func Run(runConfig *RunConfig, extMapToCodeType *ExtMapToCodeType, allStats *AllStats) FileList {
+	paths := make(chan *FileInfo, runConfig.jobs*16)
+	go func() {
+		err := GetFiles(runConfig.root, strings.Split(runConfig.filter, ";"), runConfig.detect, runConfig.NewIgnorer(), paths)
+		if err != nil {
+			log.Printf("ERROR: walk %s failed: %v", runConfig.root, err)
+		}
+		close(paths)
+	}()
+
 	factory := counter.NewCodeCounterFactory()
+	factory.SetCppPreprocess(preprocessModes[strings.ToLower(runConfig.preprocess)], runConfig.elseOfIfOne)
+	for _, v := range runConfig.languages {
+		factory.AddLanguage(v)
+	}
 
-	for _, v := range files {
-		codeType, ok := extMapToCodeType.maps[v.ext]
-		if !ok {
-			//log.Printf("ERROR: unknown code type for %s", v.fileName)
-			continue
+	results := make(chan *FileInfo, runConfig.jobs*16)
+	wg := sync.WaitGroup{}
+	for i := 0; i < runConfig.jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			CountFiles(paths, results, factory, runConfig, extMapToCodeType)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// only this goroutine touches allStats, in walk order
+	all := FileList{}
+	for v := range results {
+		for len(all) <= v.index {
+			all = append(all, nil)
 		}
+		all[v.index] = v
+	}
 
-		c, ok := factory.NewCounter(codeType)
-		if !ok {
-			log.Printf("ERROR: cannot get codecounter for %s", v.fileName)
+	files := FileList{}
+	for _, v := range all {
+		if v == nil || v.codeType == "" {
+			continue
+		}
+		if v.skipped != "" {
+			allStats.AddSkipped(v)
 			continue
 		}
+		files = append(files, v)
+		allStats.AddStat(v.codeType, &v.stat)
+		if v.generated {
+			allStats.AddGenerated(v.codeType, &v.stat)
+		}
+	}
+	return files
+}
+
+// CountFiles detects the code type of each file from paths, counts it, and
+// sends it to results. Files of unknown code type are sent with an empty
+// codeType.
+func CountFiles(paths <-chan *FileInfo, results chan<- *FileInfo, factory *counter.CodeCounterFactory, runConfig *RunConfig, extMapToCodeType *ExtMapToCodeType) {
+	for v := range paths {
+		CountFile(v, factory, runConfig, extMapToCodeType)
+		results <- v
+	}
+}
+
+// CountFile detects the code type of a file, counts it and detects whether
+// it is generated. The file is read once, its head lines for detection are
+// read ahead from the text counted.
+func CountFile(v *FileInfo, factory *counter.CodeCounterFactory, runConfig *RunConfig, extMapToCodeType *ExtMapToCodeType) {
+	file, err := counter.OpenCodeFile(v.fileName)
+	if err != nil {
+		v.codeType, v.reason = extMapToCodeType.GetCodeType(v, false, nil)
+		v.setError(err)
+		return
+	}
+	defer file.Close()
 
-		stat, ok := counter.ParseFile(c, v.fileName)
-		if !ok {
-			log.Printf("ERROR: parse file %s failed", v.fileName)
+	// binary files are skipped by their sniffed head, without reading them
+	if file.Encoding == counter.ENCODING_BINARY && !runConfig.countBinary {
+		v.codeType, v.reason = extMapToCodeType.GetCodeType(v, false, nil)
+		v.encoding = file.Encoding
+		if v.codeType != "" {
+			v.skipped = "binary"
 		}
+		return
+	}
+
+	lines, err := file.HeadLines(counter.DETECT_HEAD_LINES)
+	if err != nil {
+		v.codeType, v.reason = extMapToCodeType.GetCodeType(v, false, nil)
+		v.setError(err)
+		return
+	}
 
-		v.stat = stat
-		allStats.AddStat(codeType, &stat)
+	v.codeType, v.reason = extMapToCodeType.GetCodeType(v, runConfig.detect, lines)
+	if v.codeType == "" {
+		//log.Printf("ERROR: unknown code type for %s", v.fileName)
+		return
+	}
 
+	c, ok := factory.NewCounter(v.codeType)
+	if !ok {
+		log.Printf("ERROR: cannot get codecounter for %s", v.fileName)
+		v.codeType = ""
+		return
+	}
+
+	result, err := file.Parse(c)
+	if err != nil {
+		v.setError(err)
+		return
+	}
+
+	v.stat = result.Stat
+	v.encoding = result.Encoding
+	v.endings = result.LineEndings
+	v.hash = result.Hash
+	if result.Minified() && !runConfig.countMinified {
+		v.skipped = "minified"
+	}
+
+	if v.skipped == "" {
+		v.generated = runConfig.generatedDetector.Detect(lines)
+		if v.generated && runConfig.excludeGenerated {
+			v.skipped = "generated"
+		}
 	}
 }
 
+// setError skips a file of a known code type which cannot be read.
+func (f *FileInfo) setError(err error) {
+	if f.codeType == "" {
+		return
+	}
+	log.Printf("ERROR: parse file %s failed: %v", f.fileName, err)
+	f.skipped = "error"
+	f.err = err.Error()
+}
+
 func PrintResult(files FileList, runConfig *RunConfig, allStats *AllStats) (ret string) {
 	allStats.getMaxPrefixLen(files, runConfig)
 	SortResult(files, runConfig)
@@ -301,7 +787,11 @@ func PrintResult(files FileList, runConfig *RunConfig, allStats *AllStats) (ret
 				ret += fmt.Sprintf("[%d]: %s:  ", i, v.fileName)
 				ret += PrintIdent(allStats.maxPrefixLen - len(v.fileName))
 			}
-			ret += fmt.Sprintf("%s\n", v.stat.String())
+			ret += fmt.Sprintf("%s, Language = %s (%s), Encoding = %s, Endings = %s", v.stat.String(), v.codeType, v.reason, v.encoding, v.endings.String())
+			if v.generated {
+				ret += ", Generated"
+			}
+			ret += "\n"
 		}
 
 		ret += "\n"
@@ -310,6 +800,16 @@ func PrintResult(files FileList, runConfig *RunConfig, allStats *AllStats) (ret
 	ret += allStats.Print()
 	ret += "\n"
 
+	if runConfig.tree {
+		ret += NewDirTree(runConfig.root, files).Print(runConfig.depth)
+		ret += "\n"
+	}
+
+	if skipped := allStats.PrintSkipped(runConfig); skipped != "" {
+		ret += skipped
+		ret += "\n"
+	}
+
 	return ret
 }
 
@@ -322,7 +822,9 @@ func SortResult(files FileList, runConfig *RunConfig) {
 		"total":           func(i, j int) bool { return files[i].stat.Total < files[j].stat.Total },
 		"code":            func(i, j int) bool { return files[i].stat.Code < files[j].stat.Code },
 		"comment":         func(i, j int) bool { return files[i].stat.Comment < files[j].stat.Comment },
+		"doc":             func(i, j int) bool { return files[i].stat.Doc < files[j].stat.Doc },
 		"blank":           func(i, j int) bool { return files[i].stat.Blank < files[j].stat.Blank },
+		"disabled":        func(i, j int) bool { return files[i].stat.Disabled < files[j].stat.Disabled },
 		"comment-percent": func(i, j int) bool { return files[i].stat.CommentPercent() < files[j].stat.CommentPercent() },
 	}
 
@@ -346,12 +848,30 @@ func SortResult(files FileList, runConfig *RunConfig) {
 	}
 }
 
-func OutputResult(files FileList, runConfig *RunConfig, allStats *AllStats) {
-	ret := PrintResult(files, runConfig, allStats)
-	fmt.Printf("%s", ret)
+func OutputResult(w io.Writer, files FileList, runConfig *RunConfig, allStats *AllStats) {
+	switch runConfig.format {
+	case "json":
+		SortResult(files, runConfig)
+		if err := OutputJson(w, files, runConfig, allStats); err != nil {
+			log.Printf("ERROR: write json failed: %v", err)
+		}
+	case "cloc-yaml", "cloc-csv", "cloc-xml":
+		OutputCloc(w, runConfig.format, files, runConfig, allStats)
+	default:
+		ret := PrintResult(files, runConfig, allStats)
+		fmt.Fprintf(w, "%s", ret)
+	}
 
 	if runConfig.csvOutput {
 		OutputToCsvFile(files, runConfig, allStats)
+		if runConfig.tree {
+			OutputTreeToCsvFile(files, runConfig)
+		}
+	}
+
+	if runConfig.htmlFileName != "" {
+		SortResult(files, runConfig)
+		OutputToHtmlFile(files, runConfig, allStats)
 	}
 }
 
@@ -365,23 +885,52 @@ func OutputToCsvFile(files FileList, runConfig *RunConfig, allStats *AllStats) {
 	defer file.Close()
 
 	w := csv.NewWriter(file)
-	w.Write([]string{"FileName", "Total", "Code", "Comment", "Blank", "CommentPercent"})
+	w.Write(csvHeader)
 	for _, v := range files {
-		line := []string{}
-		if runConfig.showShortName {
-			line = append(line, v.shortName)
-		} else {
-			line = append(line, v.fileName)
-		}
-		line = append(line, v.stat.StringSlice()...)
-		w.Write(line)
+		w.Write(csvFileLine(v, runConfig))
 	}
 
-	allStats.WriteToCsvFile(w)
+	allStats.WriteToCsvFile(w, runConfig)
+
+	w.Flush()
+}
+
+func OutputTreeToCsvFile(files FileList, runConfig *RunConfig) {
+	file, err := os.OpenFile(runConfig.treeCsvFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		log.Printf("ERROR: cannot open csv file %s to write", runConfig.treeCsvFile)
+		return
+	}
+	defer file.Close()
 
+	w := csv.NewWriter(file)
+	NewDirTree(runConfig.root, files).WriteToCsvFile(w, runConfig.depth)
 	w.Flush()
 }
 
+var csvHeader = []string{"FileName", "Total", "Code", "Comment", "Doc", "Blank", "Disabled", "CommentPercent",
+	"Language", "Reason", "Encoding", "LF", "CRLF", "CR", "MixedEndings", "Generated", "Skipped"}
+
+// index of the Generated column in csvHeader
+var csvGeneratedColumn = len(csvHeader) - 2
+
+func csvFileLine(v *FileInfo, runConfig *RunConfig) []string {
+	line := []string{v.Name(runConfig)}
+	line = append(line, v.stat.StringSlice()...)
+	line = append(line, v.codeType, v.reason, v.encoding)
+	line = append(line, strconv.Itoa(v.endings.LF), strconv.Itoa(v.endings.CRLF), strconv.Itoa(v.endings.CR), strconv.FormatBool(v.endings.Mixed()))
+	line = append(line, strconv.FormatBool(v.generated), v.skipped)
+	return line
+}
+
+// padCsvLine fills line with empty columns up to the columns of csvHeader.
+func padCsvLine(line []string) []string {
+	for len(line) < len(csvHeader) {
+		line = append(line, "")
+	}
+	return line
+}
+
 func PrintIdent(num int) (ret string) {
 	return fmt.Sprintf(fmt.Sprintf("%%%ds", num), "")
 }
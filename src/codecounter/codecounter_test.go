@@ -0,0 +1,175 @@
+package main
+
+import (
+	"counter"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testFile is a file counted by countTestFiles, without reading it from disk.
+type testFile struct {
+	name      string // "/" separated and relative to the root
+	codeType  string
+	content   string
+	generated bool
+}
+
+// countTestFiles counts testFiles under root like Run, in their order, and
+// returns them with their stats, counted in 2 seconds.
+func countTestFiles(t *testing.T, root string, testFiles []testFile) (FileList, *AllStats) {
+	factory := counter.NewCodeCounterFactory()
+	allStats := NewAllStats()
+	allStats.elapsed = 2 * time.Second
+
+	files := FileList{}
+	for i, v := range testFiles {
+		if _, ok := allStats.codeTypeStats[v.codeType]; !ok {
+			allStats.AddCodeType(v.codeType)
+		}
+
+		c, ok := factory.NewCounter(v.codeType)
+		if !ok {
+			t.Fatalf("countTestFiles[%d] failed, no counter for %s", i, v.codeType)
+		}
+		result, err := counter.ParseReaderResult(c, strings.NewReader(v.content))
+		if err != nil {
+			t.Fatalf("countTestFiles[%d] failed, err = %v", i, err)
+		}
+
+		hash := sha256.Sum256([]byte(v.content))
+		fileName := filepath.Join(root, filepath.FromSlash(v.name))
+		file := &FileInfo{
+			fileName:  fileName,
+			shortName: filepath.Base(fileName),
+			ext:       filepath.Ext(fileName),
+			index:     i,
+			codeType:  v.codeType,
+			reason:    counter.DETECT_REASON_EXTENSION,
+			encoding:  counter.ENCODING_UTF8,
+			endings:   result.LineEndings,
+			generated: v.generated,
+			hash:      hex.EncodeToString(hash[:]),
+			stat:      result.Stat,
+		}
+		files = append(files, file)
+		allStats.AddStat(v.codeType, &file.stat)
+		if v.generated {
+			allStats.AddGenerated(v.codeType, &file.stat)
+		}
+	}
+	return files, allStats
+}
+
+func TestRunJobs(t *testing.T) {
+	root := t.TempDir()
+	wanted := []string{} // counted files in walk order
+	for i := 0; i < 30; i++ {
+		var name, content string
+		switch i % 3 {
+		case 0:
+			name = fmt.Sprintf("pkg%d/f%02d.go", i%4, i)
+			content = fmt.Sprintf("package pkg\n\n// f%d\nvar f%d = %d\n", i, i, i)
+		case 1:
+			name = fmt.Sprintf("pkg%d/sub/f%02d.py", i%4, i)
+			content = strings.Repeat("x = 1\n# c\n", i)
+		case 2:
+			name = fmt.Sprintf("f%02d.c", i)
+			content = fmt.Sprintf("#if 0\nint a;\n#endif\nint b%d;\n", i)
+		}
+		fileName := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+			t.Fatalf("TestRunJobs failed, err = %v", err)
+		}
+		if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+			t.Fatalf("TestRunJobs failed, err = %v", err)
+		}
+	}
+	// skipped and unknown files keep their places out of the result
+	if err := os.WriteFile(filepath.Join(root, "pkg1", "blob.go"), []byte("\x00\x00\x01\x02\x00\x00"), 0644); err != nil {
+		t.Fatalf("TestRunJobs failed, err = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "pkg1", "notes.txt"), []byte("notes\n"), 0644); err != nil {
+		t.Fatalf("TestRunJobs failed, err = %v", err)
+	}
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if ext := filepath.Ext(path); ext == ".go" || ext == ".py" || ext == ".c" {
+			if filepath.Base(path) != "blob.go" {
+				wanted = append(wanted, path)
+			}
+		}
+		return nil
+	})
+
+	codeConfigs := []CodeConfig{
+		{"go", "*.go", "", true},
+		{"python", "*.py", "", true},
+		{"c", "*.c;*.h", "", true},
+	}
+
+	var firstFiles FileList
+	var firstStats *AllStats
+	for _, jobs := range []int{1, 2, 8} {
+		runConfig := &RunConfig{root: root, filter: "*.go;*.py;*.c;*.h", detect: true, jobs: jobs, preprocess: "none",
+			generatedDetector: counter.NewGeneratedDetector()}
+		files, allStats := Count(runConfig, codeConfigs)
+		allStats.elapsed = 0
+
+		names := []string{}
+		for _, v := range files {
+			names = append(names, v.fileName)
+		}
+		if !reflect.DeepEqual(names, wanted) {
+			t.Errorf("TestRunJobs[%d] failed, files = %v, wanted = %v", jobs, names, wanted)
+		}
+		if len(allStats.skippedFiles) != 1 || allStats.skippedFiles[0].skipped != "binary" {
+			t.Errorf("TestRunJobs[%d] failed, skipped = %v", jobs, allStats.skippedFiles)
+		}
+
+		if firstFiles == nil {
+			firstFiles, firstStats = files, allStats
+			continue
+		}
+		if !reflect.DeepEqual(files, firstFiles) {
+			t.Errorf("TestRunJobs[%d] failed, files differ from the ones of 1 job", jobs)
+		}
+		if !reflect.DeepEqual(allStats, firstStats) {
+			t.Errorf("TestRunJobs[%d] failed, stats = %s, wanted = %s", jobs, allStats.Print(), firstStats.Print())
+		}
+	}
+}
+
+func TestCountFileBinary(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "blob.go")
+	if err := os.WriteFile(fileName, []byte("package blob\x00\x00\x01\x02\nvar x = 1\n"), 0644); err != nil {
+		t.Fatalf("TestCountFileBinary failed, err = %v", err)
+	}
+	extMapToCodeType := NewExtMapToCodeType()
+	extMapToCodeType.BindFiltersToCodeType("*.go", "go")
+
+	testdata := []struct {
+		countBinary bool
+		skipped     string
+		stat        counter.CodeStat
+	}{
+		// skipped before it is counted
+		{false, "binary", counter.CodeStat{}},
+		{true, "", counter.CodeStat{Total: 2, Code: 2}},
+	}
+
+	for i, v := range testdata {
+		runConfig := &RunConfig{detect: true, countBinary: v.countBinary, countMinified: true, generatedDetector: counter.NewGeneratedDetector()}
+		file := &FileInfo{fileName: fileName, shortName: "blob.go", ext: "go"}
+		CountFile(file, counter.NewCodeCounterFactory(), runConfig, extMapToCodeType)
+
+		if file.codeType != "go" || file.encoding != counter.ENCODING_BINARY || file.skipped != v.skipped || file.stat != v.stat {
+			t.Errorf("TestCountFileBinary[%d] failed, file = %+v", i, file)
+		}
+	}
+}
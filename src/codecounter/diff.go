@@ -0,0 +1,357 @@
+package main
+
+import (
+	"counter"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// status of a file in a diff
+const (
+	DIFF_ADDED     = "added"
+	DIFF_REMOVED   = "removed"
+	DIFF_MODIFIED  = "modified"
+	DIFF_UNCHANGED = "unchanged"
+	DIFF_MOVED     = "moved" // same content at another path
+)
+
+// diffFile is a counted file of a side of a diff.
+type diffFile struct {
+	path     string // "/" separated and relative to the root
+	language string
+	hash     string // may be empty for reports without hashes
+	stat     counter.CodeStat
+}
+
+// DiffSide is a counted tree or a loaded json report to diff.
+type DiffSide struct {
+	source string
+	files  map[string]*diffFile // by path
+}
+
+// NewDiffSide returns the side of the files counted under root.
+func NewDiffSide(root string, files FileList) *DiffSide {
+	side := &DiffSide{source: root, files: make(map[string]*diffFile)}
+	for _, v := range files {
+		side.add(root, v.fileName, v.codeType, v.hash, v.stat)
+	}
+	return side
+}
+
+// LoadDiffSide returns the side of a json report saved by -format json.
+func LoadDiffSide(filename string) (*DiffSide, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	report := JsonReport{}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("read report %s: %v", filename, err)
+	}
+
+	side := &DiffSide{source: filename, files: make(map[string]*diffFile)}
+	for _, v := range report.Files {
+		stat := counter.CodeStat{Total: v.Total, Code: v.Code, Comment: v.Comment, Doc: v.Doc, Blank: v.Blank, Disabled: v.Disabled}
+		side.add(report.Meta.Root, v.Path, v.Language, v.Hash, stat)
+	}
+	return side, nil
+}
+
+func (side *DiffSide) add(root, fileName, language, hash string, stat counter.CodeStat) {
+	rel, err := filepath.Rel(root, fileName)
+	if err != nil {
+		rel = fileName
+	}
+	rel = filepath.ToSlash(rel)
+	side.files[rel] = &diffFile{path: rel, language: language, hash: hash, stat: stat}
+}
+
+// FileDiff is the change of a file, with its old path if moved.
+type FileDiff struct {
+	status   string
+	path     string
+	oldPath  string
+	language string
+	old      counter.CodeStat
+	new      counter.CodeStat
+}
+
+// LanguageDiff is the change of the files of a language.
+type LanguageDiff struct {
+	language string
+	oldFiles int
+	newFiles int
+	old      counter.CodeStat
+	new      counter.CodeStat
+}
+
+func (diff *LanguageDiff) add(file *FileDiff) {
+	if file.status != DIFF_ADDED {
+		diff.oldFiles++
+		diff.old.Add(&file.old)
+	}
+	if file.status != DIFF_REMOVED {
+		diff.newFiles++
+		diff.new.Add(&file.new)
+	}
+}
+
+// Diff is the change from an old side to a new side.
+type Diff struct {
+	old       string
+	new       string
+	files     []*FileDiff     // sorted by path
+	languages []*LanguageDiff // sorted by language
+	total     LanguageDiff
+}
+
+// NewDiff compares the files of the same path of old and new, then matches
+// the files only in one of them by content hash as moved, and the rest are
+// removed or added.
+func NewDiff(old, new *DiffSide) *Diff {
+	diff := &Diff{old: old.source, new: new.source}
+
+	removed := map[string][]*diffFile{} // by hash, old files not in new
+	for _, path := range sortedPaths(old.files) {
+		o := old.files[path]
+		n, ok := new.files[path]
+		if !ok {
+			removed[o.hash] = append(removed[o.hash], o)
+			continue
+		}
+
+		// without hashes, files of the same stat are taken as unchanged
+		same := o.stat == n.stat
+		if o.hash != "" && n.hash != "" {
+			same = o.hash == n.hash
+		}
+		status := DIFF_MODIFIED
+		if same {
+			status = DIFF_UNCHANGED
+		}
+		diff.files = append(diff.files, &FileDiff{status: status, path: path, language: n.language, old: o.stat, new: n.stat})
+	}
+
+	for _, path := range sortedPaths(new.files) {
+		n := new.files[path]
+		if _, ok := old.files[path]; ok {
+			continue
+		}
+
+		if same := removed[n.hash]; n.hash != "" && len(same) > 0 {
+			removed[n.hash] = same[1:]
+			o := same[0]
+			diff.files = append(diff.files, &FileDiff{status: DIFF_MOVED, path: path, oldPath: o.path, language: n.language, old: o.stat, new: n.stat})
+			continue
+		}
+		diff.files = append(diff.files, &FileDiff{status: DIFF_ADDED, path: path, language: n.language, new: n.stat})
+	}
+
+	for _, files := range removed {
+		for _, o := range files {
+			diff.files = append(diff.files, &FileDiff{status: DIFF_REMOVED, path: o.path, language: o.language, old: o.stat})
+		}
+	}
+	sort.Slice(diff.files, func(i, j int) bool { return diff.files[i].path < diff.files[j].path })
+
+	languages := map[string]*LanguageDiff{}
+	for _, v := range diff.files {
+		language, ok := languages[v.language]
+		if !ok {
+			language = &LanguageDiff{language: v.language}
+			languages[v.language] = language
+			diff.languages = append(diff.languages, language)
+		}
+		language.add(v)
+		diff.total.add(v)
+	}
+	sort.Slice(diff.languages, func(i, j int) bool { return diff.languages[i].language < diff.languages[j].language })
+	return diff
+}
+
+func sortedPaths(files map[string]*diffFile) []string {
+	paths := make([]string, 0, len(files))
+	for k := range files {
+		paths = append(paths, k)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// delta returns the change from old to new with its sign, like "+3".
+func delta(old, new int) string {
+	return fmt.Sprintf("%+d", new-old)
+}
+
+// Print returns the changed files, all of them if showUnchanged is set, and
+// the changes of each language and of all files.
+func (diff *Diff) Print(showUnchanged bool) (ret string) {
+	ret += fmt.Sprintf("diff %s -> %s\n\n", diff.old, diff.new)
+
+	counts := map[string]int{}
+	for _, v := range diff.files {
+		counts[v.status]++
+		if v.status == DIFF_UNCHANGED && !showUnchanged {
+			continue
+		}
+
+		name := v.path
+		if v.status == DIFF_MOVED {
+			name = v.oldPath + " -> " + v.path
+		}
+		ret += fmt.Sprintf("%-9s %s (%s): Code = %s, Comment = %s, Blank = %s\n", v.status, name, v.language,
+			delta(v.old.Code, v.new.Code), delta(v.old.Comment, v.new.Comment), delta(v.old.Blank, v.new.Blank))
+	}
+	ret += "\n"
+
+	maxPrefixLen := len("total")
+	for _, v := range diff.languages {
+		if len(v.language) > maxPrefixLen {
+			maxPrefixLen = len(v.language)
+		}
+	}
+	languages := append([]*LanguageDiff{}, diff.languages...)
+	languages = append(languages, &diff.total)
+	for _, v := range languages {
+		name := v.language
+		if v == &diff.total {
+			name = "total"
+		}
+		ret += fmt.Sprintf("%s:  ", name)
+		ret += PrintIdent(maxPrefixLen - len(name))
+		ret += fmt.Sprintf("Files = %d -> %d (%s), Code = %d -> %d (%s), Comment = %d -> %d (%s), Blank = %d -> %d (%s)\n",
+			v.oldFiles, v.newFiles, delta(v.oldFiles, v.newFiles),
+			v.old.Code, v.new.Code, delta(v.old.Code, v.new.Code),
+			v.old.Comment, v.new.Comment, delta(v.old.Comment, v.new.Comment),
+			v.old.Blank, v.new.Blank, delta(v.old.Blank, v.new.Blank))
+	}
+	ret += "\n"
+
+	status := []string{}
+	for _, v := range []string{DIFF_ADDED, DIFF_REMOVED, DIFF_MODIFIED, DIFF_MOVED, DIFF_UNCHANGED} {
+		status = append(status, fmt.Sprintf("%d %s", counts[v], v))
+	}
+	ret += strings.Join(status, ", ") + "\n"
+	return ret
+}
+
+type JsonDiffStat struct {
+	Code    int `json:"code"`
+	Comment int `json:"comment"`
+	Blank   int `json:"blank"`
+}
+
+func newJsonDiffStat(stat *counter.CodeStat) JsonDiffStat {
+	return JsonDiffStat{Code: stat.Code, Comment: stat.Comment, Blank: stat.Blank}
+}
+
+func newJsonDelta(old, new *counter.CodeStat) JsonDiffStat {
+	return JsonDiffStat{Code: new.Code - old.Code, Comment: new.Comment - old.Comment, Blank: new.Blank - old.Blank}
+}
+
+type JsonFileDiff struct {
+	Status   string       `json:"status"`
+	Path     string       `json:"path"`
+	OldPath  string       `json:"oldPath,omitempty"`
+	Language string       `json:"language"`
+	Old      JsonDiffStat `json:"old"`
+	New      JsonDiffStat `json:"new"`
+	Delta    JsonDiffStat `json:"delta"`
+}
+
+type JsonLanguageDiff struct {
+	Language string       `json:"language,omitempty"`
+	OldFiles int          `json:"oldFiles"`
+	NewFiles int          `json:"newFiles"`
+	Old      JsonDiffStat `json:"old"`
+	New      JsonDiffStat `json:"new"`
+	Delta    JsonDiffStat `json:"delta"`
+}
+
+type JsonDiff struct {
+	Old       string             `json:"old"`
+	New       string             `json:"new"`
+	Files     []JsonFileDiff     `json:"files"`
+	Languages []JsonLanguageDiff `json:"languages"`
+	Total     JsonLanguageDiff   `json:"total"`
+}
+
+func newJsonLanguageDiff(v *LanguageDiff) JsonLanguageDiff {
+	return JsonLanguageDiff{
+		Language: v.language,
+		OldFiles: v.oldFiles,
+		NewFiles: v.newFiles,
+		Old:      newJsonDiffStat(&v.old),
+		New:      newJsonDiffStat(&v.new),
+		Delta:    newJsonDelta(&v.old, &v.new),
+	}
+}
+
+func (diff *Diff) WriteJson(w io.Writer) error {
+	report := JsonDiff{Old: diff.old, New: diff.new, Files: []JsonFileDiff{}, Languages: []JsonLanguageDiff{}, Total: newJsonLanguageDiff(&diff.total)}
+	for _, v := range diff.files {
+		report.Files = append(report.Files, JsonFileDiff{
+			Status:   v.status,
+			Path:     v.path,
+			OldPath:  v.oldPath,
+			Language: v.language,
+			Old:      newJsonDiffStat(&v.old),
+			New:      newJsonDiffStat(&v.new),
+			Delta:    newJsonDelta(&v.old, &v.new),
+		})
+	}
+	for _, v := range diff.languages {
+		report.Languages = append(report.Languages, newJsonLanguageDiff(v))
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// loadDiffSide counts the files under path, or loads it if it is a json
+// report.
+func loadDiffSide(path string, runConfig RunConfig, codeConfigs []CodeConfig) (*DiffSide, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return LoadDiffSide(path)
+	}
+
+	runConfig.root = path
+	files, _ := Count(&runConfig, codeConfigs)
+	return NewDiffSide(path, files), nil
+}
+
+// RunDiff runs the diff subcommand, which diffs the two roots or json
+// reports in runConfig.diffArgs, and writes the diff to w.
+func RunDiff(w io.Writer, runConfig *RunConfig, codeConfigs []CodeConfig) {
+	sides := []*DiffSide{}
+	for _, v := range runConfig.diffArgs {
+		side, err := loadDiffSide(v, *runConfig, codeConfigs)
+		if err != nil {
+			fmt.Printf("ERROR: cannot diff %s: %v", v, err)
+			return
+		}
+		sides = append(sides, side)
+	}
+
+	diff := NewDiff(sides[0], sides[1])
+	switch runConfig.format {
+	case "json":
+		if err := diff.WriteJson(w); err != nil {
+			log.Printf("ERROR: write json failed: %v", err)
+		}
+	default:
+		fmt.Fprintf(w, "%s", diff.Print(runConfig.showEachFile))
+	}
+}
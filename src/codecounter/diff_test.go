@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"counter"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestDiffSide(source string, files ...*diffFile) *DiffSide {
+	side := &DiffSide{source: source, files: make(map[string]*diffFile)}
+	for _, v := range files {
+		side.files[v.path] = v
+	}
+	return side
+}
+
+// diffStatus returns the status of each file of diff as "status path", with
+// the old path of moved files.
+func diffStatus(diff *Diff) []string {
+	ret := []string{}
+	for _, v := range diff.files {
+		if v.status == DIFF_MOVED {
+			ret = append(ret, fmt.Sprintf("%s %s -> %s", v.status, v.oldPath, v.path))
+			continue
+		}
+		ret = append(ret, fmt.Sprintf("%s %s", v.status, v.path))
+	}
+	return ret
+}
+
+func TestNewDiff(t *testing.T) {
+	small := counter.CodeStat{Total: 3, Code: 2, Blank: 1}
+	large := counter.CodeStat{Total: 9, Code: 6, Comment: 2, Blank: 1}
+
+	testdata := []struct {
+		old    []*diffFile
+		new    []*diffFile
+		wanted []string
+	}{
+		// added and removed
+		{[]*diffFile{{path: "a.rb", hash: "1", stat: small}},
+			[]*diffFile{{path: "b.rb", hash: "2", stat: large}},
+			[]string{"removed a.rb", "added b.rb"}},
+		// modified, also of the same stat with another content
+		{[]*diffFile{{path: "a.rb", hash: "1", stat: small}, {path: "b.rb", hash: "2", stat: small}},
+			[]*diffFile{{path: "a.rb", hash: "3", stat: large}, {path: "b.rb", hash: "4", stat: small}},
+			[]string{"modified a.rb", "modified b.rb"}},
+		// unchanged, also of another stat in reports of other counters
+		{[]*diffFile{{path: "a.rb", hash: "1", stat: small}, {path: "b.rb", hash: "2", stat: small}},
+			[]*diffFile{{path: "a.rb", hash: "1", stat: small}, {path: "b.rb", hash: "2", stat: large}},
+			[]string{"unchanged a.rb", "unchanged b.rb"}},
+		// without hashes, by stat
+		{[]*diffFile{{path: "a.rb", stat: small}, {path: "b.rb", stat: small}},
+			[]*diffFile{{path: "a.rb", stat: small}, {path: "b.rb", hash: "2", stat: large}},
+			[]string{"unchanged a.rb", "modified b.rb"}},
+		// moved by hash, not by stat
+		{[]*diffFile{{path: "lib/a.rb", hash: "1", stat: small}, {path: "lib/b.rb", hash: "2", stat: large}},
+			[]*diffFile{{path: "src/a.rb", hash: "1", stat: small}, {path: "src/b.rb", hash: "5", stat: large}},
+			[]string{"removed lib/b.rb", "moved lib/a.rb -> src/a.rb", "added src/b.rb"}},
+		// files without hashes are never moved
+		{[]*diffFile{{path: "lib/a.rb", stat: small}},
+			[]*diffFile{{path: "src/a.rb", stat: small}},
+			[]string{"removed lib/a.rb", "added src/a.rb"}},
+		// each copy of the same content moves once
+		{[]*diffFile{{path: "x/1.rb", hash: "1", stat: small}, {path: "x/2.rb", hash: "1", stat: small}},
+			[]*diffFile{{path: "y/1.rb", hash: "1", stat: small}, {path: "y/2.rb", hash: "1", stat: small}, {path: "y/3.rb", hash: "1", stat: small}},
+			[]string{"moved x/1.rb -> y/1.rb", "moved x/2.rb -> y/2.rb", "added y/3.rb"}},
+	}
+
+	for i, v := range testdata {
+		diff := NewDiff(newTestDiffSide("old", v.old...), newTestDiffSide("new", v.new...))
+		if status := diffStatus(diff); !reflect.DeepEqual(status, v.wanted) {
+			t.Errorf("TestNewDiff[%d] failed, status = %v, wanted = %v", i, status, v.wanted)
+		}
+	}
+}
+
+// the sides of TestDiffPrint and TestDiffWriteJson, of a ruby file
+// modified, a ruby file moved and a shell file added
+func newTestDiff() *Diff {
+	old := newTestDiffSide("v1.json",
+		&diffFile{path: "app.rb", language: "ruby", hash: "1", stat: counter.CodeStat{Total: 10, Code: 7, Comment: 1, Blank: 2}},
+		&diffFile{path: "lib/util.rb", language: "ruby", hash: "2", stat: counter.CodeStat{Total: 4, Code: 4}},
+	)
+	new := newTestDiffSide("/tmp/v2",
+		&diffFile{path: "app.rb", language: "ruby", hash: "3", stat: counter.CodeStat{Total: 12, Code: 9, Blank: 3}},
+		&diffFile{path: "util.rb", language: "ruby", hash: "2", stat: counter.CodeStat{Total: 4, Code: 4}},
+		&diffFile{path: "run.sh", language: "shell", hash: "4", stat: counter.CodeStat{Total: 2, Code: 1, Comment: 1}},
+	)
+	return NewDiff(old, new)
+}
+
+func TestDiffPrint(t *testing.T) {
+	testdata := []struct {
+		showUnchanged bool
+		wanted        string
+	}{
+		{false, `diff v1.json -> /tmp/v2
+
+modified  app.rb (ruby): Code = +2, Comment = -1, Blank = +1
+added     run.sh (shell): Code = +1, Comment = +1, Blank = +0
+moved     lib/util.rb -> util.rb (ruby): Code = +0, Comment = +0, Blank = +0
+
+ruby:   Files = 2 -> 2 (+0), Code = 11 -> 13 (+2), Comment = 1 -> 0 (-1), Blank = 2 -> 3 (+1)
+shell:  Files = 0 -> 1 (+1), Code = 0 -> 1 (+1), Comment = 0 -> 1 (+1), Blank = 0 -> 0 (+0)
+total:  Files = 2 -> 3 (+1), Code = 11 -> 14 (+3), Comment = 1 -> 1 (+0), Blank = 2 -> 3 (+1)
+
+1 added, 0 removed, 1 modified, 1 moved, 0 unchanged
+`},
+	}
+
+	for i, v := range testdata {
+		if ret := newTestDiff().Print(v.showUnchanged); ret != v.wanted {
+			t.Errorf("TestDiffPrint[%d] failed, ret = \n%s\nwanted = \n%s", i, ret, v.wanted)
+		}
+	}
+}
+
+func TestDiffWriteJson(t *testing.T) {
+	buf := bytes.Buffer{}
+	if err := newTestDiff().WriteJson(&buf); err != nil {
+		t.Fatalf("TestDiffWriteJson failed, err = %v", err)
+	}
+
+	report := JsonDiff{}
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("TestDiffWriteJson failed, err = %v", err)
+	}
+	wanted := JsonDiff{
+		Old: "v1.json",
+		New: "/tmp/v2",
+		Files: []JsonFileDiff{
+			{Status: DIFF_MODIFIED, Path: "app.rb", Language: "ruby", Old: JsonDiffStat{7, 1, 2}, New: JsonDiffStat{9, 0, 3}, Delta: JsonDiffStat{2, -1, 1}},
+			{Status: DIFF_ADDED, Path: "run.sh", Language: "shell", New: JsonDiffStat{1, 1, 0}, Delta: JsonDiffStat{1, 1, 0}},
+			{Status: DIFF_MOVED, Path: "util.rb", OldPath: "lib/util.rb", Language: "ruby", Old: JsonDiffStat{4, 0, 0}, New: JsonDiffStat{4, 0, 0}},
+		},
+		Languages: []JsonLanguageDiff{
+			{Language: "ruby", OldFiles: 2, NewFiles: 2, Old: JsonDiffStat{11, 1, 2}, New: JsonDiffStat{13, 0, 3}, Delta: JsonDiffStat{2, -1, 1}},
+			{Language: "shell", OldFiles: 0, NewFiles: 1, New: JsonDiffStat{1, 1, 0}, Delta: JsonDiffStat{1, 1, 0}},
+		},
+		Total: JsonLanguageDiff{OldFiles: 2, NewFiles: 3, Old: JsonDiffStat{11, 1, 2}, New: JsonDiffStat{14, 1, 3}, Delta: JsonDiffStat{3, 0, 1}},
+	}
+	if !reflect.DeepEqual(report, wanted) {
+		t.Errorf("TestDiffWriteJson failed, report = %+v, wanted = %+v", report, wanted)
+	}
+}
+
+func TestLoadDiffSide(t *testing.T) {
+	files := FileList{
+		{fileName: "/old/app.rb", codeType: "ruby", hash: "1", stat: counter.CodeStat{Total: 10, Code: 7, Comment: 1, Doc: 1, Blank: 2}},
+		{fileName: "/old/lib/util.rb", codeType: "ruby", hash: "2", stat: counter.CodeStat{Total: 4, Code: 4}},
+	}
+	runConfig := &RunConfig{root: "/old", filter: "*.rb"}
+
+	buf := bytes.Buffer{}
+	encoder := json.NewEncoder(&buf)
+	if err := encoder.Encode(NewJsonReport(files, runConfig, NewAllStats(), time.Now())); err != nil {
+		t.Fatalf("TestLoadDiffSide failed, err = %v", err)
+	}
+	filename := filepath.Join(t.TempDir(), "old.json")
+	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("TestLoadDiffSide failed, err = %v", err)
+	}
+
+	side, err := LoadDiffSide(filename)
+	if err != nil {
+		t.Fatalf("TestLoadDiffSide failed, err = %v", err)
+	}
+	wanted := NewDiffSide("/old", files)
+	wanted.source = filename
+	if !reflect.DeepEqual(side, wanted) {
+		t.Errorf("TestLoadDiffSide failed, side = %+v, wanted = %+v", side, wanted)
+	}
+
+	if err := os.WriteFile(filename, []byte("{"), 0644); err != nil {
+		t.Fatalf("TestLoadDiffSide failed, err = %v", err)
+	}
+	if _, err := LoadDiffSide(filename); err == nil {
+		t.Errorf("TestLoadDiffSide failed, loaded a broken report")
+	}
+}
+
+func TestRunCommandDiff(t *testing.T) {
+	old, new := t.TempDir(), t.TempDir()
+	for root, files := range map[string]map[string]string{
+		old: {
+			"a.go":     "package a\n\nfunc A() {}\n",
+			"c.go":     "package c\n",
+			"lib/b.go": "package lib\n\n// B is b\nvar B = 1\n",
+		},
+		new: {
+			"a.go":     "package a\n\nfunc A() {}\n\nfunc A2() {}\n",
+			"d.go":     "package d\n",
+			"pkg/b.go": "package lib\n\n// B is b\nvar B = 1\n",
+		},
+	} {
+		for name, content := range files {
+			fileName := filepath.Join(root, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+				t.Fatalf("TestRunCommandDiff failed, err = %v", err)
+			}
+			if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+				t.Fatalf("TestRunCommandDiff failed, err = %v", err)
+			}
+		}
+	}
+
+	// two roots
+	buf := bytes.Buffer{}
+	RunCommand([]string{"diff", "-format", "json", old, new}, &buf)
+	report := JsonDiff{}
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("TestRunCommandDiff failed, err = %v, output = \n%s", err, buf.String())
+	}
+	status := []string{}
+	for _, v := range report.Files {
+		status = append(status, fmt.Sprintf("%s %s %s %+v", v.Status, v.OldPath, v.Path, v.Delta))
+	}
+	wanted := []string{
+		"modified  a.go {Code:1 Comment:0 Blank:1}",
+		"removed  c.go {Code:-1 Comment:0 Blank:0}",
+		"added  d.go {Code:1 Comment:0 Blank:0}",
+		"moved lib/b.go pkg/b.go {Code:0 Comment:0 Blank:0}",
+	}
+	if report.Old != old || report.New != new || !reflect.DeepEqual(status, wanted) {
+		t.Errorf("TestRunCommandDiff failed, diff %s -> %s, status = %v, wanted = %v", report.Old, report.New, status, wanted)
+	}
+
+	// a json report saved by -format json and a root
+	buf.Reset()
+	RunCommand([]string{"-path", old, "-format", "json", "-csv=false"}, &buf)
+	reportFile := filepath.Join(t.TempDir(), "old.json")
+	if err := os.WriteFile(reportFile, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("TestRunCommandDiff failed, err = %v", err)
+	}
+	buf.Reset()
+	RunCommand([]string{"diff", reportFile, new}, &buf)
+	for _, v := range []string{
+		"diff " + reportFile + " -> " + new + "\n",
+		"moved     lib/b.go -> pkg/b.go (go): Code = +0, Comment = +0, Blank = +0\n",
+		"1 added, 1 removed, 1 modified, 1 moved, 0 unchanged\n",
+	} {
+		if !strings.Contains(buf.String(), v) {
+			t.Errorf("TestRunCommandDiff failed, %s not found in output = \n%s", v, buf.String())
+		}
+	}
+
+	// a diff needs two sides
+	buf.Reset()
+	RunCommand([]string{"diff", old}, &buf)
+	if buf.Len() != 0 {
+		t.Errorf("TestRunCommandDiff failed, output = \n%s", buf.String())
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"counter"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+//go:embed report.html
+var reportTemplateText string
+
+var reportTemplate = template.Must(template.New("report").Parse(reportTemplateText))
+
+// colors of the languages in charts, used in turn
+var chartColors = []string{
+	"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948",
+	"#b07aa1", "#ff9da7", "#9c755f", "#bab0ac", "#86bcb6", "#d37295",
+}
+
+// size of the charts in the html report
+const (
+	PIE_RADIUS = 100
+	BAR_WIDTH  = 400
+	BAR_HEIGHT = 20
+)
+
+type HtmlStat struct {
+	Files          int
+	Total          int
+	Code           int
+	Comment        int
+	Doc            int
+	Blank          int
+	Disabled       int
+	CommentPercent float64
+}
+
+func newHtmlStat(files int, stat *counter.CodeStat) HtmlStat {
+	return HtmlStat{files, stat.Total, stat.Code, stat.Comment, stat.Doc, stat.Blank, stat.Disabled, stat.CommentPercent()}
+}
+
+type htmlLanguage struct {
+	Name  string
+	Color string
+	Slice string  // svg path of the pie slice
+	Bar   float64 // width of the bar
+	Y     int     // top of the bar
+	HtmlStat
+}
+
+type htmlFile struct {
+	FullName  string
+	ShortName string
+	Language  string
+	Generated bool
+	HtmlStat
+}
+
+type htmlDir struct {
+	Name     string
+	Path     string
+	Open     bool
+	Children []*htmlDir
+	Entries  []htmlFile // files in the directory itself
+	HtmlStat
+}
+
+type htmlReport struct {
+	Root      string
+	Timestamp string
+	Version   string
+	SortField string
+	Reverse   bool
+	Total     HtmlStat
+	Languages []htmlLanguage
+	BarHeight int
+	Files     []htmlFile
+	Tree      *htmlDir
+	Skipped   []htmlFile
+}
+
+func newHtmlFile(v *FileInfo) htmlFile {
+	return htmlFile{v.fileName, v.shortName, v.codeType, v.generated, newHtmlStat(1, &v.stat)}
+}
+
+func newHtmlDir(dir *DirStat) *htmlDir {
+	ret := &htmlDir{Name: dir.name, Path: dir.path, Open: dir.depth == 0, HtmlStat: newHtmlStat(dir.filenum, &dir.stat)}
+	for _, v := range dir.children {
+		ret.Children = append(ret.Children, newHtmlDir(v))
+	}
+	for _, v := range dir.files {
+		ret.Entries = append(ret.Entries, newHtmlFile(v))
+	}
+	return ret
+}
+
+// newHtmlLanguages returns the stats of the languages with code, with the
+// slices of the pie chart and the bars of the bar chart of their code.
+func newHtmlLanguages(allStats *AllStats) []htmlLanguage {
+	languages := []htmlLanguage{}
+	maxCode := 0
+	for _, v := range allStats.codeTypeOrder {
+		codeTypeStat := allStats.codeTypeStats[v]
+		if codeTypeStat.filenum > 0 {
+			languages = append(languages, htmlLanguage{Name: v, HtmlStat: newHtmlStat(codeTypeStat.filenum, &codeTypeStat.stat)})
+			if codeTypeStat.stat.Code > maxCode {
+				maxCode = codeTypeStat.stat.Code
+			}
+		}
+	}
+
+	angle := 0.0
+	for i := range languages {
+		v := &languages[i]
+		v.Color = chartColors[i%len(chartColors)]
+		v.Y = i * (BAR_HEIGHT + 4)
+		if maxCode > 0 {
+			v.Bar = float64(BAR_WIDTH*v.Code) / float64(maxCode)
+		}
+		if allStats.totalStat.Code > 0 {
+			sweep := 2 * math.Pi * float64(v.Code) / float64(allStats.totalStat.Code)
+			v.Slice = pieSlice(angle, sweep)
+			angle += sweep
+		}
+	}
+	return languages
+}
+
+// pieSlice returns the svg path of a slice of the pie centered at 0, 0 from
+// angle start clockwise by sweep, in radians from the top.
+func pieSlice(start, sweep float64) string {
+	if sweep <= 0 {
+		return ""
+	}
+	if sweep >= 2*math.Pi-1e-9 {
+		// an arc cannot end where it starts
+		return fmt.Sprintf("M 0 %d A %d %d 0 1 1 0 %d A %d %d 0 1 1 0 %d Z",
+			-PIE_RADIUS, PIE_RADIUS, PIE_RADIUS, PIE_RADIUS, PIE_RADIUS, PIE_RADIUS, -PIE_RADIUS)
+	}
+
+	point := func(angle float64) (float64, float64) {
+		return PIE_RADIUS * math.Sin(angle), -PIE_RADIUS * math.Cos(angle)
+	}
+	x0, y0 := point(start)
+	x1, y1 := point(start + sweep)
+	large := 0
+	if sweep > math.Pi {
+		large = 1
+	}
+	return fmt.Sprintf("M 0 0 L %.2f %.2f A %d %d 0 %d 1 %.2f %.2f Z", x0, y0, PIE_RADIUS, PIE_RADIUS, large, x1, y1)
+}
+
+func newHtmlReport(files FileList, runConfig *RunConfig, allStats *AllStats, now time.Time) *htmlReport {
+	report := &htmlReport{
+		Root:      runConfig.root,
+		Timestamp: now.Format(time.RFC3339),
+		Version:   VERSION,
+		SortField: strings.ToLower(runConfig.sortField),
+		Reverse:   runConfig.sortReverse,
+		Total:     newHtmlStat(allStats.totalFiles, &allStats.totalStat),
+		Languages: newHtmlLanguages(allStats),
+		Tree:      newHtmlDir(NewDirTree(runConfig.root, files)),
+	}
+	report.BarHeight = len(report.Languages) * (BAR_HEIGHT + 4)
+
+	for _, v := range files {
+		report.Files = append(report.Files, newHtmlFile(v))
+	}
+	for _, v := range allStats.skippedFiles {
+		file := newHtmlFile(v)
+		file.Language = fmt.Sprintf("%s, skipped for %s", v.codeType, v.skipped)
+		report.Skipped = append(report.Skipped, file)
+	}
+	return report
+}
+
+func WriteHtml(w io.Writer, files FileList, runConfig *RunConfig, allStats *AllStats) error {
+	return reportTemplate.Execute(w, newHtmlReport(files, runConfig, allStats, time.Now()))
+}
+
+func OutputToHtmlFile(files FileList, runConfig *RunConfig, allStats *AllStats) {
+	file, err := os.OpenFile(runConfig.htmlFileName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		log.Printf("ERROR: cannot open html file %s to write", runConfig.htmlFileName)
+		return
+	}
+	defer file.Close()
+
+	if err := WriteHtml(file, files, runConfig, allStats); err != nil {
+		log.Printf("ERROR: write html file %s failed: %v", runConfig.htmlFileName, err)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"counter"
+	//"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteHtml(t *testing.T) {
+	files := FileList{
+		{fileName: "/site/index.js", shortName: "index.js", codeType: "javascript", stat: counter.CodeStat{Total: 10, Code: 6, Comment: 2, Blank: 2}},
+		{fileName: "/site/lib/util.js", shortName: "util.js", codeType: "javascript", stat: counter.CodeStat{Total: 5, Code: 4, Blank: 1}},
+		{fileName: "/site/lib/<b onmouseover=alert(1)>\".rs", shortName: "<b onmouseover=alert(1)>\".rs", codeType: "rust", stat: counter.CodeStat{Total: 3, Code: 3}, generated: true},
+	}
+	allStats := NewAllStats()
+	allStats.AddCodeType("javascript")
+	allStats.AddCodeType("rust")
+	for _, v := range files {
+		allStats.AddStat(v.codeType, &v.stat)
+	}
+	allStats.AddSkipped(&FileInfo{fileName: "/site/<img src=x onerror=alert(2)>.js", codeType: "javascript", skipped: "binary"})
+	runConfig := &RunConfig{root: "/site", sortField: "Total"}
+
+	buf := bytes.Buffer{}
+	if err := reportTemplate.Execute(&buf, newHtmlReport(files, runConfig, allStats, time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC))); err != nil {
+		t.Fatalf("TestWriteHtml failed, err = %v", err)
+	}
+	html := buf.String()
+
+	wanted := []string{
+		"<title>codecount report of /site</title>",
+		"2024-05-06T07:08:09Z, codecount 1.0.0",
+		// languages, by the code of their bars, and their total
+		`<rect x="110" y="0" width="400.00" height="20" fill="#4e79a7"><title>javascript: 10 code lines</title></rect>`,
+		`<rect x="110" y="0" width="120.00" height="20" fill="#f28e2b"><title>rust: 3 code lines</title></rect>`,
+		`<tr><td><span class="swatch" style="background: #4e79a7"></span>javascript</td><td data-value="2">2</td><td data-value="15">15</td><td data-value="10">10</td>`,
+		`<tr><td><span class="swatch" style="background: #f28e2b"></span>rust</td><td data-value="1">1</td><td data-value="3">3</td><td data-value="3">3</td>`,
+		`<tr><th>total</th><td data-value="3">3</td><td data-value="18">18</td><td data-value="13">13</td><td data-value="2">2</td>`,
+		// files, sorted in the page as by -sortfield and -reverse
+		`<table class="sortable" data-sort="total" data-reverse="false">`,
+		`<tr><td title="/site/index.js">/site/index.js</td><td>javascript</td><td data-value="10">10</td><td data-value="6">6</td>`,
+		// the directory tree, only the root open
+		"<details open>\n<summary><strong>.</strong> <span class=\"stat\">3 files, 18 total, 13 code, 2 comment, 3 blank</span></summary>",
+		"<details>\n<summary><strong>lib</strong> <span class=\"stat\">2 files, 8 total, 7 code, 0 comment, 1 blank</span></summary>",
+		`<li>util.js <span class="stat">javascript, 5 total, 4 code, 0 comment, 1 blank</span></li>`,
+		`<li>index.js <span class="stat">javascript, 10 total, 6 code, 2 comment, 2 blank</span></li>`,
+		// untrusted file names are escaped
+		`<tr class="generated"><td title="/site/lib/&lt;b onmouseover=alert(1)&gt;&#34;.rs">/site/lib/&lt;b onmouseover=alert(1)&gt;&#34;.rs</td><td>rust, generated</td>`,
+		`<li>&lt;b onmouseover=alert(1)&gt;&#34;.rs <span class="stat">rust, 3 total`,
+		`<li>/site/&lt;img src=x onerror=alert(2)&gt;.js <span class="stat">(javascript, skipped for binary)</span></li>`,
+	}
+	for i, v := range wanted {
+		if !strings.Contains(html, v) {
+			t.Errorf("TestWriteHtml[%d] failed, %s not found in html = \n%s", i, v, html)
+		}
+	}
+
+	for _, v := range []string{"<b onmouseover", "<img src=x"} {
+		if strings.Contains(html, v) {
+			t.Errorf("TestWriteHtml failed, %s not escaped", v)
+		}
+	}
+}
+
+func TestPieSlice(t *testing.T) {
+	testdata := []struct {
+		start float64
+		sweep float64
+		path  string
+	}{
+		{0, 0, ""},
+		{0, math.Pi / 2, "M 0 0 L 0.00 -100.00 A 100 100 0 0 1 100.00 -0.00 Z"},
+		{math.Pi / 2, 3 * math.Pi / 2, "M 0 0 L 100.00 -0.00 A 100 100 0 1 1 -0.00 -100.00 Z"},
+		{1, 2 * math.Pi, "M 0 -100 A 100 100 0 1 1 0 100 A 100 100 0 1 1 0 -100 Z"},
+	}
+
+	for i, v := range testdata {
+		if path := pieSlice(v.start, v.sweep); path != v.path {
+			t.Errorf("TestPieSlice[%d] failed, path = %s, wanted = %s", i, path, v.path)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bufio"
+	//"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// names of the ignore files read in each directory by Ignorer, in order of
+// precedence
+var IgnoreFileNames = []string{".gitignore", ".ignore"}
+
+// MatchGlob reports whether the "/" separated path name matches pattern,
+// where "*", "?" and "[...]" match within a path element as in path.Match,
+// and an element "**" matches any number of elements, none included.
+func MatchGlob(pattern, name string) bool {
+	return matchElements(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchElements(patterns, names []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			// skip repeated "**"
+			for len(patterns) > 0 && patterns[0] == "**" {
+				patterns = patterns[1:]
+			}
+			if len(patterns) == 0 {
+				return true
+			}
+			for i := range names {
+				if matchElements(patterns, names[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(names) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(patterns[0], names[0]); !ok {
+			return false
+		}
+		patterns = patterns[1:]
+		names = names[1:]
+	}
+	return len(names) == 0
+}
+
+// IgnorePattern is a pattern of a .gitignore file.
+type IgnorePattern struct {
+	pattern  string
+	negate   bool // "!" re-includes what earlier patterns ignore
+	dirOnly  bool // a trailing "/" matches directories only
+	anchored bool // a "/" not at the end matches paths from the base only
+}
+
+// ParseIgnorePattern parses a line of a .gitignore file, and returns false
+// for blank and comment lines.
+func ParseIgnorePattern(line string) (IgnorePattern, bool) {
+	p := IgnorePattern{}
+
+	line = strings.TrimRight(line, "\r")
+	if trimmed := strings.TrimRight(line, " "); strings.HasSuffix(trimmed, "\\") && len(trimmed) < len(line) {
+		// an escaped trailing space is kept
+		line = trimmed + " "
+	} else {
+		line = trimmed
+	}
+
+	if line == "" || line[0] == '#' {
+		return p, false
+	}
+	if line[0] == '!' {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\#") || strings.HasPrefix(line, "\\!") {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		p.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return p, false
+	}
+
+	p.pattern = line
+	return p, true
+}
+
+// Match reports whether the "/" separated path name, relative to the base of
+// the pattern, matches it.
+func (p *IgnorePattern) Match(name string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	if p.anchored {
+		return MatchGlob(p.pattern, name)
+	}
+	ok, _ := path.Match(p.pattern, path.Base(name))
+	return ok
+}
+
+// IgnoreRules are the patterns of an ignore file, which match the paths under
+// its directory base. base is "/" separated and relative to the walk root,
+// "" for the root itself.
+type IgnoreRules struct {
+	base     string
+	patterns []IgnorePattern
+}
+
+// NewIgnoreRules returns the rules of the lines of an ignore file in base.
+func NewIgnoreRules(base string, lines []string) *IgnoreRules {
+	rules := &IgnoreRules{base: base}
+	for _, v := range lines {
+		if p, ok := ParseIgnorePattern(v); ok {
+			rules.patterns = append(rules.patterns, p)
+		}
+	}
+	return rules
+}
+
+// ReadIgnoreRules returns the rules of the ignore file read from r.
+func ReadIgnoreRules(base string, r io.Reader) (*IgnoreRules, error) {
+	lines := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return NewIgnoreRules(base, lines), scanner.Err()
+}
+
+// Match reports whether a pattern matches name, relative to the walk root,
+// and whether the last matching pattern ignores it or re-includes it.
+func (rules *IgnoreRules) Match(name string, isDir bool) (matched, ignored bool) {
+	if rules.base != "" {
+		if !strings.HasPrefix(name, rules.base+"/") {
+			return false, false
+		}
+		name = name[len(rules.base)+1:]
+	}
+
+	for i := len(rules.patterns) - 1; i >= 0; i-- {
+		if rules.patterns[i].Match(name, isDir) {
+			return true, !rules.patterns[i].negate
+		}
+	}
+	return false, false
+}
+
+// Ignorer decides whether the paths met walking a tree depth first are
+// ignored, by exclude rules which always ignore what they match, then by the
+// ignore files of the directories walked into. The rules of a deeper
+// directory take precedence, as in git.
+type Ignorer struct {
+	excludes    []*IgnoreRules
+	ignoreFiles []string
+	stack       []*IgnoreRules // ignore files of the current directory and its parents
+}
+
+// NewIgnorer returns an Ignorer of the excludes rules, which reads the ignore
+// files named ignoreFiles, like IgnoreFileNames, in each directory entered.
+func NewIgnorer(excludes []*IgnoreRules, ignoreFiles []string) *Ignorer {
+	return &Ignorer{excludes: excludes, ignoreFiles: ignoreFiles}
+}
+
+// Ignored reports whether name, "/" separated and relative to the walk root,
+// is ignored. A directory must be checked by Ignored before EnterDir.
+func (ignorer *Ignorer) Ignored(name string, isDir bool) bool {
+	for _, v := range ignorer.excludes {
+		if _, ignored := v.Match(name, isDir); ignored {
+			return true
+		}
+	}
+
+	// leave the directories walked out of
+	for len(ignorer.stack) > 0 {
+		base := ignorer.stack[len(ignorer.stack)-1].base
+		if base == "" || strings.HasPrefix(name, base+"/") {
+			break
+		}
+		ignorer.stack = ignorer.stack[:len(ignorer.stack)-1]
+	}
+
+	for i := len(ignorer.stack) - 1; i >= 0; i-- {
+		if matched, ignored := ignorer.stack[i].Match(name, isDir); matched {
+			return ignored
+		}
+	}
+	return false
+}
+
+// EnterDir reads the ignore files in the directory dir, whose name relative
+// to the walk root is name.
+func (ignorer *Ignorer) EnterDir(dir, name string) error {
+	for _, v := range ignorer.ignoreFiles {
+		file, err := os.Open(filepath.Join(dir, v))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+
+		rules, err := ReadIgnoreRules(name, file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+		if len(rules.patterns) > 0 {
+			ignorer.stack = append(ignorer.stack, rules)
+		}
+	}
+	return nil
+}
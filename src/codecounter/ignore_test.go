@@ -0,0 +1,143 @@
+package main
+
+import (
+	//"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	testdata := []struct {
+		pattern string
+		name    string
+		matched bool
+	}{
+		{"*.go", "a.go", true},
+		{"*.go", "a/b.go", false},
+		{"a/*.go", "a/b.go", true},
+		{"**/*.go", "b.go", true},
+		{"**/*.go", "a/b/c.go", true},
+		{"a/**/c", "a/c", true},
+		{"a/**/c", "a/b/d/c", true},
+		{"a/**/c", "a/b/d/e", false},
+		{"a/**", "a/b/c", true},
+		{"**/gen/**", "x/gen/y.go", true},
+		{"**/gen/**", "x/general/y.go", false},
+		{"a/b?", "a/bc", true},
+		{"a/[bc]", "a/d", false},
+	}
+
+	for i, v := range testdata {
+		matched := MatchGlob(v.pattern, v.name)
+		if matched != v.matched {
+			t.Errorf("TestMatchGlob[%d] failed, matched = %v, wanted = %v", i, matched, v.matched)
+		}
+	}
+}
+
+func TestIgnoreRules(t *testing.T) {
+	rules := NewIgnoreRules("sub", []string{
+		"# comment",
+		"",
+		"*.log",
+		"!keep.log",
+		"build/",
+		"/root.txt",
+		"doc/**/*.tmp",
+		"\\#hash",
+		"space\\ ",
+	})
+
+	testdata := []struct {
+		name    string
+		isDir   bool
+		matched bool
+		ignored bool
+	}{
+		{"sub/a.log", false, true, true},
+		{"sub/x/a.log", false, true, true},
+		{"sub/keep.log", false, true, false},
+		{"a.log", false, false, false},
+		{"sub/build", true, true, true},
+		{"sub/build", false, false, false},
+		{"sub/x/build", true, true, true},
+		{"sub/root.txt", false, true, true},
+		{"sub/x/root.txt", false, false, false},
+		{"sub/doc/a/b.tmp", false, true, true},
+		{"sub/#hash", false, true, true},
+		{"sub/space ", false, true, true},
+		{"sub/a.go", false, false, false},
+	}
+
+	for i, v := range testdata {
+		matched, ignored := rules.Match(v.name, v.isDir)
+		if matched != v.matched || ignored != v.ignored {
+			t.Errorf("TestIgnoreRules[%d] failed, matched = %v, ignored = %v, wanted = %v, %v", i, matched, ignored, v.matched, v.ignored)
+		}
+	}
+}
+
+func TestIgnorer(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".gitignore":        "*.log\nout/\n",
+		"a.go":              "",
+		"a.log":             "",
+		"out/b.go":          "",
+		"sub/.gitignore":    "!keep.log\n/local.go\n",
+		"sub/.ignore":       "*.tmp\n",
+		"sub/keep.log":      "",
+		"sub/other.log":     "",
+		"sub/local.go":      "",
+		"sub/x.tmp":         "",
+		"sub/deep/local.go": "",
+		"subway/keep.log":   "",
+		"vendor/c.go":       "",
+		".git/config":       "",
+	}
+	for k, v := range files {
+		name := filepath.Join(root, filepath.FromSlash(k))
+		os.MkdirAll(filepath.Dir(name), 0755)
+		if err := os.WriteFile(name, []byte(v), 0644); err != nil {
+			t.Fatalf("TestIgnorer failed, err = %v", err)
+		}
+	}
+
+	excludes := []*IgnoreRules{NewIgnoreRules("", []string{".git/", "vendor/"})}
+	ignorer := NewIgnorer(excludes, IgnoreFileNames)
+
+	walked := []string{}
+	err := filepath.Walk(root, func(name string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, name)
+		rel = filepath.ToSlash(rel)
+
+		if f.IsDir() {
+			if rel != "." {
+				if ignorer.Ignored(rel, true) {
+					return filepath.SkipDir
+				}
+			} else {
+				rel = ""
+			}
+			return ignorer.EnterDir(name, rel)
+		}
+
+		if !ignorer.Ignored(rel, false) {
+			walked = append(walked, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("TestIgnorer failed, err = %v", err)
+	}
+
+	wanted := []string{".gitignore", "a.go", "sub/.gitignore", "sub/.ignore", "sub/deep/local.go", "sub/keep.log"}
+	if !reflect.DeepEqual(walked, wanted) {
+		t.Errorf("TestIgnorer failed, walked = %v, wanted = %v", walked, wanted)
+	}
+}
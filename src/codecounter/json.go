@@ -0,0 +1,156 @@
+package main
+
+import (
+	"counter"
+	"encoding/json"
+	//"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// version of codecounter in reports
+const VERSION = "1.0.0"
+
+type JsonReport struct {
+	Meta        JsonMeta       `json:"meta"`
+	Files       []JsonFile     `json:"files"`
+	Languages   []JsonLanguage `json:"languages"`
+	Generated   []JsonLanguage `json:"generated"`
+	Total       JsonLanguage   `json:"total"`
+	Skipped     []JsonSkipped  `json:"skipped"`
+	Directories []JsonDir      `json:"directories,omitempty"`
+}
+
+type JsonMeta struct {
+	Root      string   `json:"root"`
+	Filters   []string `json:"filters"`
+	Timestamp string   `json:"timestamp"`
+	Version   string   `json:"version"`
+}
+
+type JsonStat struct {
+	Total          int     `json:"total"`
+	Code           int     `json:"code"`
+	Comment        int     `json:"comment"`
+	Doc            int     `json:"doc"`
+	Blank          int     `json:"blank"`
+	Disabled       int     `json:"disabled"`
+	CommentPercent float64 `json:"commentPercent"`
+}
+
+type JsonEndings struct {
+	LF    int  `json:"lf"`
+	CRLF  int  `json:"crlf"`
+	CR    int  `json:"cr"`
+	Mixed bool `json:"mixed"`
+}
+
+type JsonFile struct {
+	Path      string      `json:"path"`
+	Language  string      `json:"language"`
+	Reason    string      `json:"reason"`
+	Encoding  string      `json:"encoding"`
+	Endings   JsonEndings `json:"endings"`
+	Generated bool        `json:"generated"`
+	Hash      string      `json:"hash,omitempty"`
+	JsonStat
+}
+
+type JsonLanguage struct {
+	Language string `json:"language,omitempty"`
+	Files    int    `json:"files"`
+	JsonStat
+}
+
+// JsonDir is the stat of a directory, its subdirectories included, and the
+// stat of its own files.
+type JsonDir struct {
+	Path  string       `json:"path"`
+	Depth int          `json:"depth"`
+	Files int          `json:"files"`
+	Own   JsonLanguage `json:"own"`
+	JsonStat
+}
+
+type JsonSkipped struct {
+	Path     string `json:"path"`
+	Language string `json:"language"`
+	Reason   string `json:"reason"`
+	Error    string `json:"error,omitempty"`
+}
+
+func NewJsonStat(stat *counter.CodeStat) JsonStat {
+	return JsonStat{
+		Total:          stat.Total,
+		Code:           stat.Code,
+		Comment:        stat.Comment,
+		Doc:            stat.Doc,
+		Blank:          stat.Blank,
+		Disabled:       stat.Disabled,
+		CommentPercent: stat.CommentPercent(),
+	}
+}
+
+// NewJsonReport returns the report of the counted files, the stats of
+// allStats, and the run of runConfig at now.
+func NewJsonReport(files FileList, runConfig *RunConfig, allStats *AllStats, now time.Time) *JsonReport {
+	report := &JsonReport{
+		Meta: JsonMeta{
+			Root:      runConfig.root,
+			Filters:   strings.Split(runConfig.filter, ";"),
+			Timestamp: now.Format(time.RFC3339),
+			Version:   VERSION,
+		},
+		Files:     []JsonFile{},
+		Languages: []JsonLanguage{},
+		Generated: []JsonLanguage{},
+		Total:     JsonLanguage{Files: allStats.totalFiles, JsonStat: NewJsonStat(&allStats.totalStat)},
+		Skipped:   []JsonSkipped{},
+	}
+
+	for _, v := range files {
+		report.Files = append(report.Files, JsonFile{
+			Path:      v.fileName,
+			Language:  v.codeType,
+			Reason:    v.reason,
+			Encoding:  v.encoding,
+			Endings:   JsonEndings{LF: v.endings.LF, CRLF: v.endings.CRLF, CR: v.endings.CR, Mixed: v.endings.Mixed()},
+			Generated: v.generated,
+			Hash:      v.hash,
+			JsonStat:  NewJsonStat(&v.stat),
+		})
+	}
+
+	for _, v := range allStats.codeTypeOrder {
+		if codeTypeStat := allStats.codeTypeStats[v]; codeTypeStat.filenum > 0 {
+			report.Languages = append(report.Languages, JsonLanguage{Language: v, Files: codeTypeStat.filenum, JsonStat: NewJsonStat(&codeTypeStat.stat)})
+		}
+		if codeTypeStat, ok := allStats.generatedStats[v]; ok {
+			report.Generated = append(report.Generated, JsonLanguage{Language: v, Files: codeTypeStat.filenum, JsonStat: NewJsonStat(&codeTypeStat.stat)})
+		}
+	}
+
+	for _, v := range allStats.skippedFiles {
+		report.Skipped = append(report.Skipped, JsonSkipped{Path: v.fileName, Language: v.codeType, Reason: v.skipped, Error: v.err})
+	}
+
+	if runConfig.tree {
+		NewDirTree(runConfig.root, files).Walk(runConfig.depth, func(v *DirStat) {
+			report.Directories = append(report.Directories, JsonDir{
+				Path:     v.path,
+				Depth:    v.depth,
+				Files:    v.filenum,
+				Own:      JsonLanguage{Files: v.own.filenum, JsonStat: NewJsonStat(&v.own.stat)},
+				JsonStat: NewJsonStat(&v.stat),
+			})
+		})
+	}
+	return report
+}
+
+func OutputJson(w io.Writer, files FileList, runConfig *RunConfig, allStats *AllStats) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(NewJsonReport(files, runConfig, allStats, time.Now()))
+}
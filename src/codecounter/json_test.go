@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// the json report of TestNewJsonReport, its schema is relied on by scripts
+// and by the diff subcommand
+var jsonReportGolden = `{
+  "meta": {
+    "root": "/repo",
+    "filters": [
+      "*.py",
+      "*.pyw"
+    ],
+    "timestamp": "2024-05-06T07:08:09Z",
+    "version": "1.0.0"
+  },
+  "files": [
+    {
+      "path": "/repo/tool/run.py",
+      "language": "python",
+      "reason": "extension",
+      "encoding": "utf-8",
+      "endings": {
+        "lf": 0,
+        "crlf": 3,
+        "cr": 0,
+        "mixed": false
+      },
+      "generated": false,
+      "hash": "9068009b7d7f32321e20e3e5ac36414d9f384f829971259d0eaca5dca39aaccf",
+      "total": 4,
+      "code": 2,
+      "comment": 1,
+      "doc": 0,
+      "blank": 1,
+      "disabled": 0,
+      "commentPercent": 33.33333333333333
+    },
+    {
+      "path": "/repo/tool/api_pb2.py",
+      "language": "python",
+      "reason": "extension",
+      "encoding": "utf-8",
+      "endings": {
+        "lf": 1,
+        "crlf": 0,
+        "cr": 0,
+        "mixed": false
+      },
+      "generated": true,
+      "hash": "2dce6590316a7db0827179b5645cb20d3951dfe1b99abc2262ed56b68a27e4b6",
+      "total": 2,
+      "code": 1,
+      "comment": 1,
+      "doc": 0,
+      "blank": 0,
+      "disabled": 0,
+      "commentPercent": 50
+    }
+  ],
+  "languages": [
+    {
+      "language": "python",
+      "files": 2,
+      "total": 6,
+      "code": 3,
+      "comment": 2,
+      "doc": 0,
+      "blank": 1,
+      "disabled": 0,
+      "commentPercent": 40
+    }
+  ],
+  "generated": [
+    {
+      "language": "python",
+      "files": 1,
+      "total": 2,
+      "code": 1,
+      "comment": 1,
+      "doc": 0,
+      "blank": 0,
+      "disabled": 0,
+      "commentPercent": 50
+    }
+  ],
+  "total": {
+    "files": 2,
+    "total": 6,
+    "code": 3,
+    "comment": 2,
+    "doc": 0,
+    "blank": 1,
+    "disabled": 0,
+    "commentPercent": 40
+  },
+  "skipped": [
+    {
+      "path": "/repo/tool/blob.py",
+      "language": "python",
+      "reason": "binary"
+    },
+    {
+      "path": "/repo/tool/locked.py",
+      "language": "python",
+      "reason": "error",
+      "error": "open /repo/tool/locked.py: permission denied"
+    }
+  ]
+}
+`
+
+func TestNewJsonReport(t *testing.T) {
+	files, allStats := countTestFiles(t, "/repo", []testFile{
+		{name: "tool/run.py", codeType: "python", content: "import os\r\n\r\n# run it\r\nprint(os.name)"},
+		{name: "tool/api_pb2.py", codeType: "python", content: "# Generated by the protocol buffer compiler.  DO NOT EDIT!\nx = 1", generated: true},
+	})
+	allStats.AddSkipped(&FileInfo{fileName: "/repo/tool/blob.py", codeType: "python", skipped: "binary"})
+	allStats.AddSkipped(&FileInfo{fileName: "/repo/tool/locked.py", codeType: "python", skipped: "error", err: "open /repo/tool/locked.py: permission denied"})
+	runConfig := &RunConfig{root: "/repo", filter: "*.py;*.pyw"}
+
+	buf := bytes.Buffer{}
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(NewJsonReport(files, runConfig, allStats, time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC))); err != nil {
+		t.Fatalf("TestNewJsonReport failed, err = %v", err)
+	}
+	if buf.String() != jsonReportGolden {
+		t.Errorf("TestNewJsonReport failed, report = \n%s\nwanted = \n%s", buf.String(), jsonReportGolden)
+	}
+}
+
+func TestRunCommandJson(t *testing.T) {
+	root := t.TempDir()
+	testFiles := map[string]string{
+		"main.rb":           "require 'json'\n\n# entry\nputs JSON.dump([])\n",
+		"lib/util.rb":       "def util\n  1\nend\n",
+		"lib/schema_pb.rb":  "# Generated by the protocol buffer compiler.  DO NOT EDIT!\nmodule Schema\nend\n",
+		"lib/blob.rb":       "\x00\x00\x01\x02\x00\x00",
+		"config/app.yaml":   "name: app\n",
+		"vendor/.git/HEAD":  "ref: refs/heads/main\n",
+		"scripts/deploy.sh": "#!/bin/sh\n# deploy\nmake install\n",
+	}
+	for name, content := range testFiles {
+		fileName := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+			t.Fatalf("TestRunCommandJson failed, err = %v", err)
+		}
+		if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+			t.Fatalf("TestRunCommandJson failed, err = %v", err)
+		}
+	}
+
+	buf := bytes.Buffer{}
+	RunCommand([]string{"-path", root, "-format", "json", "-csv=false", "-sortfield", "fullname", "-reverse=false"}, &buf)
+
+	report := JsonReport{}
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("TestRunCommandJson failed, err = %v, output = \n%s", err, buf.String())
+	}
+
+	if report.Meta.Root != root || report.Meta.Version != VERSION {
+		t.Errorf("TestRunCommandJson failed, meta = %+v", report.Meta)
+	}
+
+	// yaml is not counted without -all-languages, nor files under .git
+	files := []string{}
+	for _, v := range report.Files {
+		rel, _ := filepath.Rel(root, v.Path)
+		files = append(files, fmt.Sprintf("%s %s %d/%d/%d/%d %v", filepath.ToSlash(rel), v.Language, v.Total, v.Code, v.Comment, v.Blank, v.Generated))
+	}
+	wanted := []string{
+		"lib/schema_pb.rb ruby 3/2/1/0 true",
+		"lib/util.rb ruby 3/3/0/0 false",
+		"main.rb ruby 4/2/1/1 false",
+		"scripts/deploy.sh shell 3/1/2/0 false",
+	}
+	if !reflect.DeepEqual(files, wanted) {
+		t.Errorf("TestRunCommandJson failed, files = %v, wanted = %v", files, wanted)
+	}
+
+	// in the order of the code configs
+	languages := []string{}
+	for _, v := range report.Languages {
+		languages = append(languages, fmt.Sprintf("%s %d %d", v.Language, v.Files, v.Code))
+	}
+	if wanted := []string{"shell 1 1", "ruby 3 7"}; !reflect.DeepEqual(languages, wanted) {
+		t.Errorf("TestRunCommandJson failed, languages = %v, wanted = %v", languages, wanted)
+	}
+	if len(report.Generated) != 1 || report.Generated[0].Language != "ruby" || report.Generated[0].Files != 1 {
+		t.Errorf("TestRunCommandJson failed, generated = %+v", report.Generated)
+	}
+	if report.Total.Files != 4 || report.Total.Total != 13 || report.Total.Code != 8 {
+		t.Errorf("TestRunCommandJson failed, total = %+v", report.Total)
+	}
+	if len(report.Skipped) != 1 || report.Skipped[0].Path != filepath.Join(root, "lib", "blob.rb") || report.Skipped[0].Reason != "binary" {
+		t.Errorf("TestRunCommandJson failed, skipped = %+v", report.Skipped)
+	}
+}
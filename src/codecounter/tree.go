@@ -0,0 +1,124 @@
+package main
+
+import (
+	"counter"
+	"encoding/csv"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DirStat is the stat of the files under a directory, its subdirectories
+// included.
+type DirStat struct {
+	name     string // base name, "." for the root
+	path     string // "/" separated and relative to the root, "." for the root
+	depth    int    // 0 for the root
+	filenum  int
+	stat     counter.CodeStat
+	own      CodeTypeStat // of the files in the directory itself
+	children []*DirStat   // sorted by name
+	files    FileList     // files in the directory itself
+}
+
+// NewDirTree returns the tree of the directories under root of files, with
+// the stat of each directory.
+func NewDirTree(root string, files FileList) *DirStat {
+	tree := &DirStat{name: ".", path: "."}
+	for _, v := range files {
+		rel, err := filepath.Rel(root, v.fileName)
+		if err != nil {
+			rel = v.fileName
+		}
+
+		dir := tree
+		dir.add(v)
+		elements := strings.Split(filepath.ToSlash(rel), "/")
+		for _, name := range elements[:len(elements)-1] {
+			dir = dir.child(name)
+			dir.add(v)
+		}
+		dir.files = append(dir.files, v)
+		dir.own.filenum++
+		dir.own.stat.Add(&v.stat)
+	}
+	tree.sort()
+	return tree
+}
+
+func (dir *DirStat) add(file *FileInfo) {
+	dir.filenum++
+	dir.stat.Add(&file.stat)
+}
+
+func (dir *DirStat) child(name string) *DirStat {
+	for _, v := range dir.children {
+		if v.name == name {
+			return v
+		}
+	}
+
+	path := name
+	if dir.path != "." {
+		path = dir.path + "/" + name
+	}
+	child := &DirStat{name: name, path: path, depth: dir.depth + 1}
+	dir.children = append(dir.children, child)
+	return child
+}
+
+// Walk calls walkFunc for dir and the directories under it, parents first,
+// down to maxDepth below the root, or all of them if maxDepth is 0.
+func (dir *DirStat) Walk(maxDepth int, walkFunc func(dir *DirStat)) {
+	if maxDepth > 0 && dir.depth > maxDepth {
+		return
+	}
+
+	walkFunc(dir)
+	for _, v := range dir.children {
+		v.Walk(maxDepth, walkFunc)
+	}
+}
+
+// Print returns the tree of dir down to maxDepth, each directory indented
+// under its parent with its stat, its subdirectories included.
+func (dir *DirStat) Print(maxDepth int) (ret string) {
+	maxPrefixLen := 0
+	dir.Walk(maxDepth, func(v *DirStat) {
+		if n := 2*v.depth + len(v.name); n > maxPrefixLen {
+			maxPrefixLen = n
+		}
+	})
+
+	dir.Walk(maxDepth, func(v *DirStat) {
+		ret += PrintIdent(2 * v.depth)
+		ret += fmt.Sprintf("%s:  ", v.name)
+		ret += PrintIdent(maxPrefixLen - 2*v.depth - len(v.name))
+		ret += fmt.Sprintf("Files = %6d, Own = %6d, %s\n", v.filenum, v.own.filenum, v.stat.String())
+	})
+	return ret
+}
+
+// WriteToCsvFile writes a row of each directory down to maxDepth, with its
+// stat and the stat of its own files.
+func (dir *DirStat) WriteToCsvFile(w *csv.Writer, maxDepth int) {
+	w.Write([]string{"Directory", "Depth", "Files", "Total", "Code", "Comment", "Doc", "Blank", "Disabled", "CommentPercent",
+		"OwnFiles", "OwnTotal", "OwnCode", "OwnComment", "OwnDoc", "OwnBlank", "OwnDisabled", "OwnCommentPercent"})
+
+	dir.Walk(maxDepth, func(v *DirStat) {
+		line := []string{v.path, strconv.Itoa(v.depth), strconv.Itoa(v.filenum)}
+		line = append(line, v.stat.StringSlice()...)
+		line = append(line, strconv.Itoa(v.own.filenum))
+		line = append(line, v.own.stat.StringSlice()...)
+		w.Write(line)
+	})
+}
+
+func (dir *DirStat) sort() {
+	sort.Slice(dir.children, func(i, j int) bool { return dir.children[i].name < dir.children[j].name })
+	for _, v := range dir.children {
+		v.sort()
+	}
+}
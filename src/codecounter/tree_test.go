@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"counter"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// files of the tree tests, out of the order of their directories
+var treeTestFiles = FileList{
+	{fileName: "/work/docs/conf.py", codeType: "python", stat: counter.CodeStat{Total: 2, Code: 2}},
+	{fileName: "/work/build.py", codeType: "python", stat: counter.CodeStat{Total: 6, Code: 3, Comment: 2, Blank: 1}},
+	{fileName: "/work/cmd/tool/main.rs", codeType: "rust", stat: counter.CodeStat{Total: 20, Code: 15, Comment: 3, Doc: 1, Blank: 2}},
+	{fileName: "/work/cmd/gen.py", codeType: "python", stat: counter.CodeStat{Total: 4, Code: 4}},
+	{fileName: "/work/cmd/tool/args.rs", codeType: "rust", stat: counter.CodeStat{Total: 8, Code: 6, Blank: 2}},
+}
+
+func TestDirTreeWalk(t *testing.T) {
+	testdata := []struct {
+		maxDepth int
+		wanted   []string // path:depth:files:own files:total
+	}{
+		{0, []string{".:0:5:1:40", "cmd:1:3:1:32", "cmd/tool:2:2:2:28", "docs:1:1:1:2"}},
+		{1, []string{".:0:5:1:40", "cmd:1:3:1:32", "docs:1:1:1:2"}},
+		{2, []string{".:0:5:1:40", "cmd:1:3:1:32", "cmd/tool:2:2:2:28", "docs:1:1:1:2"}},
+	}
+
+	tree := NewDirTree("/work", treeTestFiles)
+	for i, v := range testdata {
+		dirs := []string{}
+		tree.Walk(v.maxDepth, func(dir *DirStat) {
+			dirs = append(dirs, fmt.Sprintf("%s:%d:%d:%d:%d", dir.path, dir.depth, dir.filenum, dir.own.filenum, dir.stat.Total))
+		})
+		if !reflect.DeepEqual(dirs, v.wanted) {
+			t.Errorf("TestDirTreeWalk[%d] failed, dirs = %v, wanted = %v", i, dirs, v.wanted)
+		}
+	}
+}
+
+func TestDirTreePrint(t *testing.T) {
+	tree := NewDirTree("/work", treeTestFiles)
+
+	wanted := `.:       Files =      5, Own =      1, Total =     40, Code =     30, Comment =      5, Doc =      1, Blank =      5, Disabled =      0, CommentPercent = 14.29%
+  cmd:   Files =      3, Own =      1, Total =     32, Code =     25, Comment =      3, Doc =      1, Blank =      4, Disabled =      0, CommentPercent = 10.71%
+  docs:  Files =      1, Own =      1, Total =      2, Code =      2, Comment =      0, Doc =      0, Blank =      0, Disabled =      0, CommentPercent = 0.00%
+`
+	if ret := tree.Print(1); ret != wanted {
+		t.Errorf("TestDirTreePrint failed, ret = \n%s\nwanted = \n%s", ret, wanted)
+	}
+}
+
+func TestDirTreeWriteToCsvFile(t *testing.T) {
+	tree := NewDirTree("/work", treeTestFiles)
+
+	buf := bytes.Buffer{}
+	w := csv.NewWriter(&buf)
+	tree.WriteToCsvFile(w, 0)
+	w.Flush()
+
+	wanted := `Directory,Depth,Files,Total,Code,Comment,Doc,Blank,Disabled,CommentPercent,OwnFiles,OwnTotal,OwnCode,OwnComment,OwnDoc,OwnBlank,OwnDisabled,OwnCommentPercent
+.,0,5,40,30,5,1,5,0,14.29%,1,6,3,2,0,1,0,40.00%
+cmd,1,3,32,25,3,1,4,0,10.71%,1,4,4,0,0,0,0,0.00%
+cmd/tool,2,2,28,21,3,1,4,0,12.50%,2,28,21,3,1,4,0,12.50%
+docs,1,1,2,2,0,0,0,0,0.00%,1,2,2,0,0,0,0,0.00%
+`
+	if buf.String() != wanted {
+		t.Errorf("TestDirTreeWriteToCsvFile failed, csv = \n%s\nwanted = \n%s", buf.String(), wanted)
+	}
+}
+
+func TestNewJsonReportDirectories(t *testing.T) {
+	testdata := []struct {
+		tree   bool
+		depth  int
+		wanted []JsonDir
+	}{
+		{false, 0, nil},
+		{true, 1, []JsonDir{
+			{Path: ".", Depth: 0, Files: 5, Own: JsonLanguage{Files: 1, JsonStat: NewJsonStat(&counter.CodeStat{Total: 6, Code: 3, Comment: 2, Blank: 1})},
+				JsonStat: NewJsonStat(&counter.CodeStat{Total: 40, Code: 30, Comment: 5, Doc: 1, Blank: 5})},
+			{Path: "cmd", Depth: 1, Files: 3, Own: JsonLanguage{Files: 1, JsonStat: NewJsonStat(&counter.CodeStat{Total: 4, Code: 4})},
+				JsonStat: NewJsonStat(&counter.CodeStat{Total: 32, Code: 25, Comment: 3, Doc: 1, Blank: 4})},
+			{Path: "docs", Depth: 1, Files: 1, Own: JsonLanguage{Files: 1, JsonStat: NewJsonStat(&counter.CodeStat{Total: 2, Code: 2})},
+				JsonStat: NewJsonStat(&counter.CodeStat{Total: 2, Code: 2})},
+		}},
+	}
+
+	for i, v := range testdata {
+		runConfig := &RunConfig{root: "/work", filter: "*.py;*.rs", tree: v.tree, depth: v.depth}
+		report := NewJsonReport(treeTestFiles, runConfig, NewAllStats(), time.Now())
+		if !reflect.DeepEqual(report.Directories, v.wanted) {
+			t.Errorf("TestNewJsonReportDirectories[%d] failed, directories = %+v, wanted = %+v", i, report.Directories, v.wanted)
+		}
+	}
+}
+
+func TestRunConfigCheckDepth(t *testing.T) {
+	testdata := []struct {
+		args []string
+		ok   bool
+	}{
+		{[]string{"-tree"}, true},
+		{[]string{"-tree", "-depth", "0"}, true},
+		{[]string{"-tree", "-depth", "2"}, true},
+		{[]string{"-tree", "-depth", "-1"}, false},
+		{[]string{"-depth=-3"}, false},
+	}
+
+	root := t.TempDir()
+	for i, v := range testdata {
+		runConfig := RunConfig{}
+		runConfig.Parse(append([]string{"-path", root}, v.args...), nil)
+		if ok := runConfig.Check(); ok != v.ok {
+			t.Errorf("TestRunConfigCheckDepth[%d] failed, ok = %v, wanted = %v", i, ok, v.ok)
+		}
+	}
+}
+
+func TestRunCommandTree(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a.py", "pkg/b.py", "pkg/sub/c.py"} {
+		fileName := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+			t.Fatalf("TestRunCommandTree failed, err = %v", err)
+		}
+		if err := os.WriteFile(fileName, []byte("x = 1\n"), 0644); err != nil {
+			t.Fatalf("TestRunCommandTree failed, err = %v", err)
+		}
+	}
+
+	buf := bytes.Buffer{}
+	RunCommand([]string{"-path", root, "-tree", "-depth", "1", "-csv=false"}, &buf)
+
+	// the directories down to -depth, after the totals
+	wanted := `.:      Files =      3, Own =      1, Total =      3, Code =      3, Comment =      0, Doc =      0, Blank =      0, Disabled =      0, CommentPercent = 0.00%
+  pkg:  Files =      2, Own =      1, Total =      2, Code =      2, Comment =      0, Doc =      0, Blank =      0, Disabled =      0, CommentPercent = 0.00%
+`
+	if !strings.Contains(buf.String(), "\n\n"+wanted+"\n") {
+		t.Errorf("TestRunCommandTree failed, output = \n%s\nwanted tree = \n%s", buf.String(), wanted)
+	}
+}
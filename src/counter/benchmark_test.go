@@ -0,0 +1,87 @@
+package counter
+
+import (
+	"bufio"
+	"bytes"
+	//"fmt"
+	"io"
+	"os"
+	"testing"
+)
+
+// the testdata files are scaled up to about this size
+const BENCHMARK_DATA_SIZE = 1024 * 1024
+
+var benchmarkFiles = []struct {
+	name     string
+	filename string
+}{
+	{"cpp", "test1.cpp"},
+	{"go", "test1.go"},
+	{"csharp", "test1.cs"},
+	{"erlang", "test1.erl"},
+	{"python", "test1.py"},
+	{"rust", "test1.rs"},
+	{"javascript", "test1.js"},
+	{"pascal", "test1.pas"},
+}
+
+// benchmarkData returns the content of a testdata file repeated up to
+// BENCHMARK_DATA_SIZE.
+func benchmarkData(b *testing.B, filename string) []byte {
+	content, err := os.ReadFile(os.Args[len(os.Args)-1] + "\\src\\testdata\\" + filename)
+	if err != nil {
+		b.Skipf("cannot read testdata: %v", err)
+	}
+
+	data := make([]byte, 0, BENCHMARK_DATA_SIZE+len(content)+1)
+	for len(data) < BENCHMARK_DATA_SIZE {
+		data = append(data, content...)
+		data = append(data, '\n')
+	}
+	return data
+}
+
+// parseReaderByString counts like ParseReader did before its fast path, with
+// a string allocated for each line, to measure the speedup.
+func parseReaderByString(counter CodeCounter, r io.Reader) (stat CodeStat, err error) {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && io.EOF != err {
+			return stat, err
+		}
+		lineStat := counter.ParseLine(line)
+		stat.Add(&lineStat)
+		if io.EOF == err {
+			return stat, nil
+		}
+	}
+}
+
+func benchmarkParse(b *testing.B, parse func(CodeCounter, io.Reader) (CodeStat, error)) {
+	factory := NewCodeCounterFactory()
+	for _, v := range benchmarkFiles {
+		b.Run(v.name, func(b *testing.B) {
+			data := benchmarkData(b, v.filename)
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				counter, _ := factory.NewCounter(v.name)
+				if _, err := parse(counter, bytes.NewReader(data)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParseReader(b *testing.B) {
+	benchmarkParse(b, ParseReader)
+}
+
+func BenchmarkParseReaderByString(b *testing.B) {
+	benchmarkParse(b, parseReaderByString)
+}
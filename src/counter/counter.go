@@ -2,24 +2,31 @@ package counter
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash"
 	"strconv"
 	//"fmt"
 	"io"
-	"log"
 	"os"
+	"sync"
+	"unsafe"
 )
 
 type CodeStat struct {
-	Total   int
-	Code    int
-	Comment int
-	Blank   int
+	Total    int
+	Code     int
+	Comment  int
+	Doc      int // documentation lines, also counted in Comment
+	Blank    int
+	Disabled int // lines in preprocessor regions like #if 0
 }
 
 func (codeStat *CodeStat) String() string {
-	return fmt.Sprintf("Total = %6d, Code = %6d, Comment = %6d, Blank = %6d, CommentPercent = %2.2f%%",
-		codeStat.Total, codeStat.Code, codeStat.Comment, codeStat.Blank, codeStat.CommentPercent())
+	return fmt.Sprintf("Total = %6d, Code = %6d, Comment = %6d, Doc = %6d, Blank = %6d, Disabled = %6d, CommentPercent = %2.2f%%",
+		codeStat.Total, codeStat.Code, codeStat.Comment, codeStat.Doc, codeStat.Blank, codeStat.Disabled, codeStat.CommentPercent())
 }
 
 func (codeStat *CodeStat) StringSlice() []string {
@@ -27,7 +34,9 @@ func (codeStat *CodeStat) StringSlice() []string {
 		strconv.Itoa(codeStat.Total),
 		strconv.Itoa(codeStat.Code),
 		strconv.Itoa(codeStat.Comment),
+		strconv.Itoa(codeStat.Doc),
 		strconv.Itoa(codeStat.Blank),
+		strconv.Itoa(codeStat.Disabled),
 		fmt.Sprintf("%2.2f%%", codeStat.CommentPercent()),
 	}
 
@@ -37,7 +46,9 @@ func (codeStat *CodeStat) Add(rhs *CodeStat) {
 	codeStat.Total += rhs.Total
 	codeStat.Code += rhs.Code
 	codeStat.Comment += rhs.Comment
+	codeStat.Doc += rhs.Doc
 	codeStat.Blank += rhs.Blank
+	codeStat.Disabled += rhs.Disabled
 }
 
 func (codeStat *CodeStat) CommentPercent() float64 {
@@ -47,33 +58,230 @@ func (codeStat *CodeStat) CommentPercent() float64 {
 	return float64(codeStat.Comment) / float64(codeStat.Code+codeStat.Comment) * 100
 }
 
+// CodeCounter counts the lines of a file one by one. ParseLine must not keep
+// line, or any part of it, after it returns, because ParseReader reuses its
+// memory for the next line.
 type CodeCounter interface {
 	Clear()
 	ParseLine(line string) (stat CodeStat)
 }
 
-func ParseFile(counter CodeCounter, filename string) (stat CodeStat, ok bool) {
-	file, err := os.Open(filename)
+// DocCounter is a CodeCounter of a language whose doc comments are known only
+// at the line after them, like go, where they are the comment lines right
+// before a declaration. After ParseLine, DocLines returns how many of the
+// comment lines right before the line are doc comments, and ParseReader
+// counts them as Doc of those comment lines.
+type DocCounter interface {
+	DocLines() int
+}
+
+// FileResult is the result of counting a file.
+type FileResult struct {
+	Stat        CodeStat
+	Encoding    string
+	LineEndings LineEndings
+	Bytes       int64  // size of the decoded text
+	Hash        string // hex sha256 of the content of a file, set by ParseFileResult
+}
+
+// minified text is at least this long, with longer lines in average
+const (
+	MINIFIED_MIN_BYTES   = 1024
+	MINIFIED_LINE_LENGTH = 250
+)
+
+// Binary reports whether the file is not text, see DetectEncoding.
+func (result *FileResult) Binary() bool {
+	return result.Encoding == ENCODING_BINARY
+}
+
+// Minified reports whether the file looks minified or machine generated by
+// its extreme average line length, like a javascript bundle on one line.
+func (result *FileResult) Minified() bool {
+	return result.Bytes >= MINIFIED_MIN_BYTES && result.Bytes > int64(result.Stat.Total)*MINIFIED_LINE_LENGTH
+}
+
+// initial size of the buffer of ParseReader, it grows for longer lines
+const PARSE_BUFFER_SIZE = 64 * 1024
+
+var lineReaderPool = sync.Pool{
+	New: func() interface{} { return &lineReader{buf: make([]byte, PARSE_BUFFER_SIZE)} },
+}
+
+// ParseReader counts the lines read from r with counter. On a read error it
+// returns the stat of the lines read before it and the error.
+func ParseReader(counter CodeCounter, r io.Reader) (stat CodeStat, err error) {
+	result, err := ParseReaderResult(counter, r)
+	return result.Stat, err
+}
+
+// ParseReaderResult counts the lines read from r with counter, and their
+// line endings. Lines end in "\n", "\r\n" or a bare "\r".
+//
+// The lines are passed to counter straight from a reused buffer, so counting
+// allocates nothing per line.
+func ParseReaderResult(counter CodeCounter, r io.Reader) (result FileResult, err error) {
+	reader := lineReaderPool.Get().(*lineReader)
+	reader.reset(r)
+	defer func() {
+		reader.reset(nil)
+		lineReaderPool.Put(reader)
+	}()
+
+	docCounter, _ := counter.(DocCounter)
+	for {
+		line, ending, err := reader.next()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+
+		lineStat := counter.ParseLine(unsafe.String(unsafe.SliceData(line), len(line)))
+		result.Stat.Add(&lineStat)
+		if docCounter != nil {
+			result.Stat.Doc += docCounter.DocLines()
+		}
+		//fmt.Printf("line = %s\nlineStat = %s, state = %d\n", strings.TrimSpace(line), lineStat.String(), c.state)
+
+		result.Bytes += int64(len(line))
+		switch ending {
+		case LINE_ENDING_LF:
+			result.LineEndings.LF++
+			result.Bytes++
+		case LINE_ENDING_CRLF:
+			result.LineEndings.CRLF++
+			result.Bytes += 2
+		case LINE_ENDING_CR:
+			result.LineEndings.CR++
+			result.Bytes++
+		}
+	}
+}
+
+// ParseFile counts the lines of a file with counter, see ParseFileResult.
+func ParseFile(counter CodeCounter, filename string) (stat CodeStat, err error) {
+	result, err := ParseFileResult(counter, filename)
+	return result.Stat, err
+}
+
+// ParseFileResult counts the lines of a file with counter after decoding it
+// to UTF-8, see DecodeReader and ParseReaderResult, and hashes its content.
+func ParseFileResult(counter CodeCounter, filename string) (result FileResult, err error) {
+	file, err := OpenCodeFile(filename)
 	if err != nil {
-		log.Printf("ERROR: cannot open file %s", filename)
-		return stat, false
+		return result, err
 	}
 	defer file.Close()
 
-	reader := bufio.NewReader(file)
+	return file.Parse(counter)
+}
+
+// size of the decoded text read ahead for HeadLines
+const HEAD_SIZE = 16 * 1024
 
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil && io.EOF != err {
+// CodeFile is a file opened to count, which is read only once: its encoding
+// is detected and its head lines are read ahead from the reader its lines are
+// counted from.
+type CodeFile struct {
+	filename string
+	file     *os.File
+	hash     hash.Hash
+	reader   io.Reader
+	head     *bufio.Reader // reader with the head read ahead, nil before HeadLines
+	Encoding string
+}
+
+// OpenCodeFile opens a file and detects its encoding, see DecodeReader.
+func OpenCodeFile(filename string) (*CodeFile, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &CodeFile{filename: filename, file: file, hash: sha256.New()}
+	f.reader, f.Encoding, err = DecodeReader(io.TeeReader(file, f.hash))
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("read %s: %v", filename, err)
+	}
+	return f, nil
+}
+
+// HeadLines returns the first n lines of the file, decoded to UTF-8, from the
+// first HEAD_SIZE bytes of its text, which are read ahead and counted later.
+func (f *CodeFile) HeadLines(n int) (lines []string, err error) {
+	if f.head == nil {
+		f.head = bufio.NewReaderSize(f.reader, HEAD_SIZE)
+		f.reader = f.head
+	}
+	head, err := f.head.Peek(HEAD_SIZE)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("read %s: %v", f.filename, err)
+	}
+
+	reader := &lineReader{buf: make([]byte, 1024)}
+	reader.reset(bytes.NewReader(head))
+	for len(lines) < n {
+		line, ending, err := reader.next()
+		if err != nil {
 			break
 		}
-		lineStat := counter.ParseLine(line)
-		stat.Add(&lineStat)
-		//fmt.Printf("line = %s\nlineStat = %s, state = %d\n", strings.TrimSpace(line), lineStat.String(), c.state)
-
-		if io.EOF == err {
+		// the last line of a full head may go on after it
+		if ending == LINE_ENDING_NONE && len(head) == HEAD_SIZE {
 			break
 		}
+		lines = append(lines, string(line))
+	}
+	return lines, nil
+}
+
+// Parse counts the lines of the file with counter, see ParseReaderResult,
+// and hashes its content.
+func (f *CodeFile) Parse(counter CodeCounter) (result FileResult, err error) {
+	result, err = ParseReaderResult(counter, f.reader)
+	result.Encoding = f.Encoding
+	if err != nil {
+		return result, fmt.Errorf("read %s: %v", f.filename, err)
+	}
+	result.Hash = hex.EncodeToString(f.hash.Sum(nil))
+	return result, nil
+}
+
+func (f *CodeFile) Close() error {
+	return f.file.Close()
+}
+
+func peekByte(s string, i int) byte {
+	if i < 0 || i >= len(s) {
+		return 0
+	}
+	return s[i]
+}
+
+// countByte returns the number of consecutive b starting at s[i].
+func countByte(s string, i int, b byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == b {
+		n++
+	}
+	return n
+}
+
+// isDocComment reports whether the comment opened by "//" or "/*", with rest
+// after the opener, is a documentation comment like "///", "//!", "/**" or
+// "/*!". marker is the second rune of the opener. Banners like "////" or
+// "/***" and the empty "/**/" are not.
+func isDocComment(rest string, marker byte) bool {
+	if len(rest) == 0 {
+		return false
+	}
+	if rest[0] == '!' {
+		return true
+	}
+	if rest[0] != marker {
+		return false
 	}
-	return stat, true
+	return len(rest) == 1 || rest[1] != marker && !(marker == '*' && rest[1] == '/')
 }
@@ -1,27 +1,104 @@
 package counter
 
 import (
-//"fmt"
+	//"fmt"
+	"sync"
 )
 
+// CodeCounterConstructor returns a new CodeCounter.
+type CodeCounterConstructor func() CodeCounter
+
+var (
+	registryLock sync.RWMutex
+	registry     = make(map[string]CodeCounterConstructor)
+)
+
+// Register makes counters made by constructor available by name in the
+// factories created after it, replacing any counter of the same name. It is
+// meant for other packages to plug in their own counters, usually in init.
+func Register(name string, constructor CodeCounterConstructor) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registry[name] = constructor
+}
+
+func init() {
+	Register("go", func() CodeCounter { return NewGoCodeCounter() })
+	Register("cpp", func() CodeCounter { return NewCppCodeCounter() })
+	Register("c", func() CodeCounter { return NewCppCodeCounter() })
+	Register("java", func() CodeCounter { return NewCppCodeCounter() })
+	Register("objc", func() CodeCounter { return NewCppCodeCounter() })
+	Register("csharp", func() CodeCounter { return NewCSharpCodeCounter() })
+	Register("erlang", func() CodeCounter { return NewErlangCodeCounter() })
+	Register("python", func() CodeCounter { return NewPythonCodeCounter() })
+	Register("rust", func() CodeCounter { return NewRustCodeCounter() })
+	Register("javascript", func() CodeCounter { return NewJavaScriptCodeCounter() })
+	Register("typescript", func() CodeCounter { return NewJavaScriptCodeCounter() })
+	Register("pascal", func() CodeCounter { return NewPascalCodeCounter() })
+
+	for _, v := range BuiltinLanguageDefinitions() {
+		if _, ok := registry[v.Name]; !ok {
+			Register(v.Name, tableCodeCounterConstructor(v))
+		}
+	}
+}
+
+// CodeCounterFactory makes a new counter for each file. After it is set up,
+// NewCounter may be called from many goroutines.
 type CodeCounterFactory struct {
-	maps map[string]CodeCounter
+	maps          map[string]CodeCounterConstructor
+	cppPreprocess int
+	cppElseOfIf1  bool
 }
 
 func NewCodeCounterFactory() *CodeCounterFactory {
 	factory := &CodeCounterFactory{}
-	factory.maps = make(map[string]CodeCounter)
-	factory.maps["go"] = NewGoCodeCounter()
-	factory.maps["cpp"] = NewCppCodeCounter()
-	factory.maps["c"] = NewCppCodeCounter()
-	factory.maps["java"] = NewCppCodeCounter()
-	factory.maps["erlang"] = NewErlangCodeCounter()
+	factory.maps = make(map[string]CodeCounterConstructor)
+
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	for k, v := range registry {
+		factory.maps[k] = v
+	}
 	return factory
 }
 
+// NewCounter returns a new counter for the language name, which shares no
+// state with other counters.
 func (factory *CodeCounterFactory) NewCounter(name string) (counter CodeCounter, ok bool) {
-	if counter, ok = factory.maps[name]; ok {
-		counter.Clear()
+	constructor, ok := factory.maps[name]
+	if !ok {
+		return nil, false
 	}
-	return counter, ok
+
+	counter = constructor()
+	if c, ok := counter.(*CppCodeCounter); ok {
+		c.SetPreprocess(factory.cppPreprocess, factory.cppElseOfIf1)
+	}
+	return counter, true
+}
+
+// Register makes counters made by constructor available by name in this
+// factory only, replacing any counter of the same name.
+func (factory *CodeCounterFactory) Register(name string, constructor CodeCounterConstructor) {
+	factory.maps[name] = constructor
+}
+
+// AddLanguage adds a table-driven counter for def, replacing any counter of
+// the same name.
+func (factory *CodeCounterFactory) AddLanguage(def *LanguageDefinition) {
+	factory.Register(def.Name, tableCodeCounterConstructor(def))
+}
+
+// SetCppPreprocess sets how the c/c++ counters count lines in #if 0 regions,
+// see CppCodeCounter.SetPreprocess.
+func (factory *CodeCounterFactory) SetCppPreprocess(mode int, elseOfIfOne bool) {
+	factory.cppPreprocess = mode
+	factory.cppElseOfIf1 = elseOfIfOne
+}
+
+func tableCodeCounterConstructor(def *LanguageDefinition) CodeCounterConstructor {
+	// sort the delimiters once, not for every file
+	sorted := NewTableCodeCounter(def).def
+	return func() CodeCounter { return &TableCodeCounter{def: sorted} }
 }
@@ -0,0 +1,87 @@
+package counter
+
+import (
+	//"fmt"
+	"testing"
+)
+
+type testCodeCounter struct {
+	lines int
+}
+
+func (c *testCodeCounter) Clear() { c.lines = 0 }
+
+func (c *testCodeCounter) ParseLine(line string) (stat CodeStat) {
+	c.lines++
+	return CodeStat{Total: 1, Code: c.lines}
+}
+
+func TestCodeCounterFactoryNewCounter(t *testing.T) {
+	factory := NewCodeCounterFactory()
+
+	c1, ok1 := factory.NewCounter("cpp")
+	c2, ok2 := factory.NewCounter("cpp")
+	if !ok1 || !ok2 {
+		t.Errorf("TestCodeCounterFactoryNewCounter failed, no counter for cpp")
+		return
+	}
+
+	// a block comment left open in one counter does not leak into another
+	c1.ParseLine("/* ab")
+	stat := c2.ParseLine("ab")
+	wanted := CodeStat{Total: 1, Code: 1}
+	if c1 == c2 || stat != wanted {
+		t.Errorf("TestCodeCounterFactoryNewCounter failed, stat = %s, wanted = %s", stat.String(), wanted.String())
+	}
+
+	if _, ok := factory.NewCounter("unknown"); ok {
+		t.Errorf("TestCodeCounterFactoryNewCounter failed, got counter for unknown")
+	}
+}
+
+func TestCodeCounterFactoryRegister(t *testing.T) {
+	before := NewCodeCounterFactory()
+	Register("test-register", func() CodeCounter { return &testCodeCounter{} })
+	t.Cleanup(func() {
+		registryLock.Lock()
+		defer registryLock.Unlock()
+		delete(registry, "test-register")
+	})
+	after := NewCodeCounterFactory()
+
+	if _, ok := before.NewCounter("test-register"); ok {
+		t.Errorf("TestCodeCounterFactoryRegister failed, registered counter in earlier factory")
+	}
+
+	c, ok := after.NewCounter("test-register")
+	if !ok {
+		t.Errorf("TestCodeCounterFactoryRegister failed, no registered counter")
+		return
+	}
+	c.ParseLine("a")
+	if _, ok := c.(*testCodeCounter); !ok {
+		t.Errorf("TestCodeCounterFactoryRegister failed, counter is %T", c)
+	}
+
+	// registering in a factory does not change other factories
+	after.Register("test-local", func() CodeCounter { return &testCodeCounter{} })
+	if _, ok := NewCodeCounterFactory().NewCounter("test-local"); ok {
+		t.Errorf("TestCodeCounterFactoryRegister failed, local counter in other factory")
+	}
+}
+
+func TestCodeCounterFactorySetCppPreprocess(t *testing.T) {
+	factory := NewCodeCounterFactory()
+	factory.SetCppPreprocess(CPP_PREPROCESS_COMMENT, false)
+
+	c, _ := factory.NewCounter("cpp")
+	stat := CodeStat{}
+	for _, line := range []string{"#if 0", "a", "#endif"} {
+		lineStat := c.ParseLine(line)
+		stat.Add(&lineStat)
+	}
+
+	if stat.Comment == 0 {
+		t.Errorf("TestCodeCounterFactorySetCppPreprocess failed, stat = %s", stat.String())
+	}
+}
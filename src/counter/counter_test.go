@@ -0,0 +1,176 @@
+package counter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestParseReader(t *testing.T) {
+	testdata := []struct {
+		content string
+		stat    CodeStat
+	}{
+		{"", CodeStat{}},
+		{"a := 1", CodeStat{Total: 1, Code: 1}},
+		{"a := 1\n// b\n\n/* c\nd */", CodeStat{Total: 5, Code: 1, Comment: 3, Blank: 1}},
+	}
+
+	for i, v := range testdata {
+		counter, _ := NewCodeCounterFactory().NewCounter("go")
+
+		stat, err := ParseReader(counter, strings.NewReader(v.content))
+		if err != nil {
+			t.Errorf("TestParseReader[%d] failed, err = %v", i, err)
+			continue
+		}
+
+		if stat != v.stat {
+			t.Errorf("TestParseReader[%d] failed, stat = %s, wanted = %s", i, stat.String(), v.stat.String())
+			continue
+		}
+	}
+}
+
+func TestParseReaderError(t *testing.T) {
+	readErr := errors.New("read failed")
+	r := io.MultiReader(strings.NewReader("a := 1\nb := 2\n"), iotest.ErrReader(readErr))
+
+	counter, _ := NewCodeCounterFactory().NewCounter("go")
+	wanted := CodeStat{Total: 2, Code: 2}
+
+	stat, err := ParseReader(counter, r)
+	if err != readErr {
+		t.Errorf("TestParseReaderError failed, err = %v, wanted = %v", err, readErr)
+		return
+	}
+
+	if stat != wanted {
+		t.Errorf("TestParseReaderError failed, stat = %s, wanted = %s", stat.String(), wanted.String())
+		return
+	}
+}
+
+func TestParseFileNotExist(t *testing.T) {
+	counter, _ := NewCodeCounterFactory().NewCounter("go")
+
+	if _, err := ParseFile(counter, "not-exist.go"); err == nil {
+		t.Errorf("TestParseFileNotExist failed, err = nil")
+	}
+}
+
+func TestParseReaderLongLine(t *testing.T) {
+	long := "a := \"" + strings.Repeat("x", 3*PARSE_BUFFER_SIZE) + "\" // c"
+	content := "/* a\n" + long + "\nb */ b := 1\n" + long
+
+	counter, _ := NewCodeCounterFactory().NewCounter("go")
+	wanted := CodeStat{Total: 4, Code: 2, Comment: 4}
+
+	stat, err := ParseReader(counter, strings.NewReader(content))
+	if err != nil {
+		t.Errorf("TestParseReaderLongLine failed, err = %v", err)
+		return
+	}
+
+	if stat != wanted {
+		t.Errorf("TestParseReaderLongLine failed, stat = %s, wanted = %s", stat.String(), wanted.String())
+		return
+	}
+}
+
+func TestFileResultMinified(t *testing.T) {
+	testdata := []struct {
+		content  string
+		minified bool
+	}{
+		{"var a = 1;\nvar b = 2;", false},
+		{strings.Repeat("var a = 1;", 20), false},
+		{strings.Repeat("var a = 1;", 200), true},
+		{strings.Repeat("var a = 1;", 200) + strings.Repeat("\n", 10), false},
+	}
+
+	for i, v := range testdata {
+		counter, _ := NewCodeCounterFactory().NewCounter("javascript")
+
+		result, _ := ParseReaderResult(counter, strings.NewReader(v.content))
+		if result.Minified() != v.minified {
+			t.Errorf("TestFileResultMinified[%d] failed, minified = %v, bytes = %d, lines = %d", i, result.Minified(), result.Bytes, result.Stat.Total)
+		}
+	}
+}
+
+func TestParseFileResultHash(t *testing.T) {
+	dir := t.TempDir()
+	contents := []string{"a := 1", "a := 1", "a := 2"}
+	hashes := []string{}
+	for i, v := range contents {
+		name := filepath.Join(dir, fmt.Sprintf("%d.go", i))
+		if err := os.WriteFile(name, []byte(v), 0644); err != nil {
+			t.Fatalf("TestParseFileResultHash failed, err = %v", err)
+		}
+
+		counter, _ := NewCodeCounterFactory().NewCounter("go")
+		result, err := ParseFileResult(counter, name)
+		if err != nil {
+			t.Fatalf("TestParseFileResultHash[%d] failed, err = %v", i, err)
+		}
+		hashes = append(hashes, result.Hash)
+	}
+
+	if hashes[0] == "" || hashes[0] != hashes[1] || hashes[0] == hashes[2] {
+		t.Errorf("TestParseFileResultHash failed, hashes = %v", hashes)
+	}
+}
+
+func TestCodeFileHeadLines(t *testing.T) {
+	dir := t.TempDir()
+	testdata := []struct {
+		content string
+		n       int
+		lines   []string
+		stat    CodeStat
+	}{
+		{"", 2, nil, CodeStat{}},
+		{"// a\r\n\r\nb := 1\r\n", 2, []string{"// a", ""}, CodeStat{Total: 3, Code: 1, Comment: 1, Blank: 1}},
+		{"\xef\xbb\xbfa := 1\nb := 2", 5, []string{"a := 1", "b := 2"}, CodeStat{Total: 2, Code: 2}},
+		// the head ends in the middle of the second line
+		{strings.Repeat("x", HEAD_SIZE-10) + "\n" + strings.Repeat("y", 20), 5, []string{strings.Repeat("x", HEAD_SIZE-10)}, CodeStat{Total: 2, Code: 2}},
+	}
+
+	for i, v := range testdata {
+		name := filepath.Join(dir, fmt.Sprintf("%d.go", i))
+		if err := os.WriteFile(name, []byte(v.content), 0644); err != nil {
+			t.Fatalf("TestCodeFileHeadLines failed, err = %v", err)
+		}
+
+		file, err := OpenCodeFile(name)
+		if err != nil {
+			t.Errorf("TestCodeFileHeadLines[%d] failed, err = %v", i, err)
+			continue
+		}
+		lines, err := file.HeadLines(v.n)
+		if err != nil || strings.Join(lines, "|") != strings.Join(v.lines, "|") || len(lines) != len(v.lines) {
+			t.Errorf("TestCodeFileHeadLines[%d] failed, lines = %q, err = %v, wanted = %q", i, lines, err, v.lines)
+		}
+
+		// the lines read ahead are counted too
+		counter, _ := NewCodeCounterFactory().NewCounter("go")
+		result, err := file.Parse(counter)
+		file.Close()
+		if err != nil || result.Stat != v.stat {
+			t.Errorf("TestCodeFileHeadLines[%d] failed, stat = %s, err = %v, wanted = %s", i, result.Stat.String(), err, v.stat.String())
+			continue
+		}
+
+		counter, _ = NewCodeCounterFactory().NewCounter("go")
+		wanted, _ := ParseFileResult(counter, name)
+		if result.Hash != wanted.Hash || result.Encoding != wanted.Encoding {
+			t.Errorf("TestCodeFileHeadLines[%d] failed, hash = %s, encoding = %s, wanted = %s, %s", i, result.Hash, result.Encoding, wanted.Hash, wanted.Encoding)
+		}
+	}
+}
@@ -16,17 +16,54 @@ const (
 	CPP_CODE_COUNT_STATE_STRING_ESCAPE      = 7
 	CPP_CODE_COUNT_STATE_CHAR               = 8
 	CPP_CODE_COUNT_STATE_CHAR_ESCAPE        = 9
+	CPP_CODE_COUNT_STATE_RAW_STRING_DELIM   = 10
+	CPP_CODE_COUNT_STATE_RAW_STRING         = 11
+	CPP_CODE_COUNT_STATE_RAW_STRING_CLOSE   = 12
 )
 
+// how lines in #if 0 regions are counted
+const (
+	CPP_PREPROCESS_NONE     = 0 // as ordinary code
+	CPP_PREPROCESS_COMMENT  = 1 // as comment
+	CPP_PREPROCESS_DISABLED = 2 // as disabled
+)
+
+// cppCondition is a #if ... #endif group.
+type cppCondition struct {
+	disabled bool // current branch is disabled
+	taken    bool // a previous branch is always taken, like #if 1
+	allFalse bool // all previous branches are never taken, like #if 0
+}
+
 type CppCodeCounter struct {
 	state int
+	delim []byte // delimiter of current raw string
+	match int    // number of delimiter bytes matched after ')'
+	word  []byte // identifier before current byte, for raw string prefix
+	doc   bool   // current comment is a documentation comment
+
+	preprocess  int
+	elseOfIfOne bool // also disable the #else branch of #if 1
+	conditions  []cppCondition
 }
 
 func NewCppCodeCounter() *CppCodeCounter {
 	return &CppCodeCounter{}
 }
 
-func (c *CppCodeCounter) Clear() { c.state = CPP_CODE_COUNT_STATE_INIT }
+func (c *CppCodeCounter) Clear() {
+	c.state = CPP_CODE_COUNT_STATE_INIT
+	c.delim = c.delim[:0]
+	c.match = 0
+	c.conditions = c.conditions[:0]
+}
+
+// SetPreprocess sets how lines in #if 0 regions are counted, and whether the
+// #else branch of #if 1 counts as such a region too.
+func (c *CppCodeCounter) SetPreprocess(mode int, elseOfIfOne bool) {
+	c.preprocess = mode
+	c.elseOfIfOne = elseOfIfOne
+}
 
 func (c *CppCodeCounter) ParseLine(line string) (stat CodeStat) {
 	stat.Total = 1
@@ -36,6 +73,18 @@ func (c *CppCodeCounter) ParseLine(line string) (stat CodeStat) {
 		switch c.state {
 		case CPP_CODE_COUNT_STATE_BLOCK_COMMENT:
 			stat.Comment = 1
+			if c.doc {
+				stat.Doc = 1
+			}
+		case CPP_CODE_COUNT_STATE_LINE_COMMENT:
+			// spliced onto the previous line comment
+			stat.Comment = 1
+			if c.doc {
+				stat.Doc = 1
+			}
+			c.state = CPP_CODE_COUNT_STATE_INIT
+		case CPP_CODE_COUNT_STATE_RAW_STRING:
+			stat.Code = 1
 		default:
 			stat.Blank = 1
 		}
@@ -43,14 +92,34 @@ func (c *CppCodeCounter) ParseLine(line string) (stat CodeStat) {
 		return stat
 	}
 
+	if c.preprocess != CPP_PREPROCESS_NONE && c.state == CPP_CODE_COUNT_STATE_INIT {
+		wasDisabled := c.isDisabled()
+		if line[0] == '#' {
+			c.parseDirective(line[1:])
+		}
+
+		// the directives opening and closing a disabled region are code
+		if wasDisabled && c.isDisabled() {
+			if c.preprocess == CPP_PREPROCESS_COMMENT {
+				stat.Comment = 1
+			} else {
+				stat.Disabled = 1
+			}
+			return stat
+		}
+	}
+
 	hasCode := false
-	hasComment := false
+	hasComment := c.state == CPP_CODE_COUNT_STATE_LINE_COMMENT
+	hasDoc := hasComment && c.doc
+	c.word = c.word[:0]
 
 	/*if c.state != CPP_CODE_COUNT_STATE_BLOCK_COMMENT && c.state != CPP_CODE_COUNT_STATE_STRING && c.state != CPP_CODE_COUNT_STATE_STRING_ESCAPE {
 		c.state = CPP_CODE_COUNT_STATE_INIT
 	}*/
 
-	for _, v := range line {
+	for i := 0; i < len(line); i++ {
+		v := line[i]
 		//fmt.Printf("v = %c, state = %d\n", v, c.state)
 
 		if c.state == CPP_CODE_COUNT_STATE_LINE_COMMENT {
@@ -81,10 +150,13 @@ func (c *CppCodeCounter) ParseLine(line string) (stat CodeStat) {
 			switch v {
 			case '*':
 				c.state = CPP_CODE_COUNT_STATE_BLOCK_COMMENT
+				c.doc = isDocComment(line[i+1:], '*')
 				hasComment = true
 			case '/':
 				c.state = CPP_CODE_COUNT_STATE_LINE_COMMENT
+				c.doc = isDocComment(line[i+1:], '/')
 				hasComment = true
+				hasDoc = hasDoc || c.doc
 			case '"':
 				c.state = CPP_CODE_COUNT_STATE_STRING
 				hasCode = true
@@ -98,6 +170,7 @@ func (c *CppCodeCounter) ParseLine(line string) (stat CodeStat) {
 
 		case CPP_CODE_COUNT_STATE_BLOCK_COMMENT:
 			hasComment = true
+			hasDoc = hasDoc || c.doc
 			if v == '*' {
 				c.state = CPP_CODE_COUNT_STATE_BLOCK_COMMENT_STAR
 			}
@@ -118,7 +191,12 @@ func (c *CppCodeCounter) ParseLine(line string) (stat CodeStat) {
 			case '/':
 				c.state = CPP_CODE_COUNT_STATE_SLASH
 			case '"':
-				c.state = CPP_CODE_COUNT_STATE_STRING
+				if cppIsRawStringPrefix(c.word) {
+					c.state = CPP_CODE_COUNT_STATE_RAW_STRING_DELIM
+					c.delim = c.delim[:0]
+				} else {
+					c.state = CPP_CODE_COUNT_STATE_STRING
+				}
 				hasCode = true
 			case '\'':
 				c.state = CPP_CODE_COUNT_STATE_CHAR
@@ -149,6 +227,39 @@ func (c *CppCodeCounter) ParseLine(line string) (stat CodeStat) {
 		case CPP_CODE_COUNT_STATE_CHAR_ESCAPE:
 			c.state = CPP_CODE_COUNT_STATE_CHAR
 
+		case CPP_CODE_COUNT_STATE_RAW_STRING_DELIM:
+			hasCode = true
+			if v == '(' {
+				c.state = CPP_CODE_COUNT_STATE_RAW_STRING
+			} else {
+				c.delim = append(c.delim, v)
+			}
+
+		case CPP_CODE_COUNT_STATE_RAW_STRING:
+			hasCode = true
+			if v == ')' {
+				c.state = CPP_CODE_COUNT_STATE_RAW_STRING_CLOSE
+				c.match = 0
+			}
+
+		case CPP_CODE_COUNT_STATE_RAW_STRING_CLOSE:
+			hasCode = true
+			switch {
+			case c.match < len(c.delim) && v == c.delim[c.match]:
+				c.match++
+			case c.match == len(c.delim) && v == '"':
+				c.state = CPP_CODE_COUNT_STATE_CODE
+			case v == ')':
+				c.match = 0
+			default:
+				c.state = CPP_CODE_COUNT_STATE_RAW_STRING
+			}
+		}
+
+		if cppIsIdent(v) && (c.state == CPP_CODE_COUNT_STATE_CODE || c.state == CPP_CODE_COUNT_STATE_INIT) {
+			c.word = append(c.word, v)
+		} else {
+			c.word = c.word[:0]
 		}
 	}
 
@@ -160,18 +271,110 @@ func (c *CppCodeCounter) ParseLine(line string) (stat CodeStat) {
 		stat.Comment = 1
 	}
 
+	if hasDoc {
+		stat.Doc = 1
+	}
+
 	switch c.state {
 	case CPP_CODE_COUNT_STATE_BLOCK_COMMENT:
 		break
 	case CPP_CODE_COUNT_STATE_BLOCK_COMMENT_STAR:
 		c.state = CPP_CODE_COUNT_STATE_BLOCK_COMMENT
+	case CPP_CODE_COUNT_STATE_LINE_COMMENT:
+		// a backslash before the newline splices the next line onto the
+		// comment
+		if !strings.HasSuffix(line, "\\") {
+			c.state = CPP_CODE_COUNT_STATE_INIT
+		}
 	case CPP_CODE_COUNT_STATE_STRING:
 		break
 	case CPP_CODE_COUNT_STATE_STRING_ESCAPE:
 		c.state = CPP_CODE_COUNT_STATE_STRING
+	case CPP_CODE_COUNT_STATE_CHAR_ESCAPE:
+		c.state = CPP_CODE_COUNT_STATE_CHAR
+	case CPP_CODE_COUNT_STATE_RAW_STRING:
+		break
+	case CPP_CODE_COUNT_STATE_RAW_STRING_CLOSE:
+		c.state = CPP_CODE_COUNT_STATE_RAW_STRING
 	default:
 		c.state = CPP_CODE_COUNT_STATE_INIT
 	}
 
 	return stat
 }
+
+// cppIsRawStringPrefix reports whether word is the encoding prefix of a raw
+// string literal, like R"delim(...)delim".
+func cppIsRawStringPrefix(word []byte) bool {
+	switch string(word) {
+	case "R", "LR", "uR", "UR", "u8R":
+		return true
+	}
+	return false
+}
+
+func cppIsIdent(v byte) bool {
+	return v == '_' || v >= 'a' && v <= 'z' || v >= 'A' && v <= 'Z' || v >= '0' && v <= '9'
+}
+
+func (c *CppCodeCounter) isDisabled() bool {
+	for _, v := range c.conditions {
+		if v.disabled {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDirective tracks the #if/#ifdef/#ifndef/#elif/#else/#endif nesting,
+// directive is the line after '#'.
+func (c *CppCodeCounter) parseDirective(directive string) {
+	fields := strings.Fields(directive)
+	if len(fields) == 0 {
+		return
+	}
+
+	value := ""
+	if len(fields) > 1 {
+		value = fields[1]
+	}
+
+	switch fields[0] {
+	case "if":
+		cond := cppCondition{}
+		switch value {
+		case "0":
+			cond.disabled = true
+			cond.allFalse = true
+		case "1":
+			cond.taken = true
+		}
+		c.conditions = append(c.conditions, cond)
+
+	case "ifdef", "ifndef":
+		c.conditions = append(c.conditions, cppCondition{})
+
+	case "elif", "elifdef", "elifndef", "else":
+		if len(c.conditions) == 0 {
+			return
+		}
+		cond := &c.conditions[len(c.conditions)-1]
+		switch {
+		case cond.taken:
+			cond.disabled = c.elseOfIfOne
+		case fields[0] == "elif" && value == "0":
+			cond.disabled = true
+		case fields[0] == "elif" && value == "1" && cond.allFalse:
+			cond.disabled = false
+			cond.taken = true
+		default:
+			cond.disabled = false
+			cond.allFalse = cond.allFalse && fields[0] == "else"
+		}
+
+	case "endif":
+		if len(c.conditions) > 0 {
+			c.conditions = c.conditions[:len(c.conditions)-1]
+		}
+	}
+}
@@ -18,12 +18,21 @@ func TestCppCodeCounterParseLine(t *testing.T) {
 		{"//ab//", CodeStat{Total: 1, Comment: 1}},
 		{"ab/*", CodeStat{Total: 1, Code: 1, Comment: 1}},
 		{"/*ab", CodeStat{Total: 1, Comment: 1}},
+		{"/** ab */", CodeStat{Total: 1, Comment: 1, Doc: 1}},
+		{"///ab", CodeStat{Total: 1, Comment: 1, Doc: 1}},
+		{"//!ab", CodeStat{Total: 1, Comment: 1, Doc: 1}},
+		{"/**/ab", CodeStat{Total: 1, Code: 1, Comment: 1}},
 		{"ab/*tt**/ cc ", CodeStat{Total: 1, Code: 1, Comment: 1}},
 		{" /*aa**/ \tcc\t ff d", CodeStat{Total: 1, Code: 1, Comment: 1}},
 		{" \"//\"/*aa*/ \tcc\t ff\"\\t\" d", CodeStat{Total: 1, Code: 1, Comment: 1}},
 		{" ff/\"as/\"", CodeStat{Total: 1, Code: 1}},
 		{"var x= 3", CodeStat{Total: 1, Code: 1}},
 		{"a\"\\\"", CodeStat{Total: 1, Code: 1}},
+		{"R\"(\")\" // c", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"R\"(\\)\" // c", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"R\"xy()\" // )xy\"", CodeStat{Total: 1, Code: 1}},
+		{"u8R\"-(/*)-\" // c", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"FOR\"//\"", CodeStat{Total: 1, Code: 1}},
 	}
 
 	for i, v := range testdata {
@@ -38,15 +47,97 @@ func TestCppCodeCounterParseLine(t *testing.T) {
 	}
 }
 
+func TestCppCodeCounterParseLines(t *testing.T) {
+	testdata := []struct {
+		lines []string
+		stat  CodeStat
+	}{
+		{[]string{"// a \\", "b", "c"}, CodeStat{Total: 3, Code: 1, Comment: 2}},
+		{[]string{"x; // a \\", "", "c"}, CodeStat{Total: 3, Code: 2, Comment: 2}},
+		{[]string{"\"a \\", "// b\"", "c"}, CodeStat{Total: 3, Code: 3}},
+		{[]string{"'\\", "'", "c"}, CodeStat{Total: 3, Code: 3}},
+		{[]string{"R\"(", "", "// )\"", "c"}, CodeStat{Total: 4, Code: 4}},
+		{[]string{"R\"a(", ")b\" /*", ")a\" /*", "*/"}, CodeStat{Total: 4, Code: 3, Comment: 2}},
+	}
+
+	for i, v := range testdata {
+		counter, _ := NewCodeCounterFactory().NewCounter("cpp")
+
+		stat := CodeStat{}
+		for _, line := range v.lines {
+			lineStat := counter.ParseLine(line)
+			stat.Add(&lineStat)
+		}
+
+		if stat != v.stat {
+			t.Errorf("TestCppCodeCounterParseLines[%d] failed, stat = %s, wanted = %s", i, stat.String(), v.stat.String())
+			continue
+		}
+	}
+}
+
+func TestCppCodeCounterPreprocess(t *testing.T) {
+	lines := []string{
+		"#if 0",
+		"int a = 'x;",
+		"",
+		"#ifdef X",
+		"#endif",
+		"#else",
+		"int b;",
+		"#endif",
+		"#if 1",
+		"int c;",
+		"#elif Y",
+		"int d;",
+		"#else",
+		"/* e */",
+		"#endif",
+		"#if X",
+		"#elif 0",
+		"int f;",
+		"#else",
+		"int g;",
+		"#endif",
+	}
+
+	testdata := []struct {
+		mode        int
+		elseOfIfOne bool
+		stat        CodeStat
+	}{
+		{CPP_PREPROCESS_NONE, false, CodeStat{Total: 21, Code: 19, Comment: 1, Blank: 1}},
+		{CPP_PREPROCESS_COMMENT, false, CodeStat{Total: 21, Code: 15, Comment: 5, Blank: 1}},
+		{CPP_PREPROCESS_DISABLED, false, CodeStat{Total: 21, Code: 15, Comment: 1, Blank: 1, Disabled: 4}},
+		{CPP_PREPROCESS_DISABLED, true, CodeStat{Total: 21, Code: 13, Blank: 1, Disabled: 7}},
+	}
+
+	for i, v := range testdata {
+		counter, _ := NewCodeCounterFactory().NewCounter("cpp")
+		counter.(*CppCodeCounter).SetPreprocess(v.mode, v.elseOfIfOne)
+
+		stat := CodeStat{}
+		for _, line := range lines {
+			lineStat := counter.ParseLine(line)
+			stat.Add(&lineStat)
+		}
+
+		if stat != v.stat {
+			t.Errorf("TestCppCodeCounterPreprocess[%d] failed, stat = %s, wanted = %s", i, stat.String(), v.stat.String())
+			continue
+		}
+	}
+}
+
 func TestCppCodeCounterParseFile(t *testing.T) {
 	filename := os.Args[len(os.Args)-1] + "\\src\\testdata\\test1.cpp"
 
 	counter, _ := NewCodeCounterFactory().NewCounter("cpp")
-	wanted := CodeStat{Total: 21, Code: 13, Comment: 8, Blank: 2}
+	wanted := CodeStat{Total: 20, Code: 13, Comment: 8, Doc: 2, Blank: 1}
 
-	stat, ok := ParseFile(counter, filename)
-	if !ok {
-		t.Errorf("TestCppCodeCounterParseFile failed, ParseFile failed")
+	stat, err := ParseFile(counter, filename)
+	if err != nil {
+		t.Errorf("TestCppCodeCounterParseFile failed, ParseFile failed: %v", err)
 		return
 	}
 
@@ -55,3 +146,21 @@ func TestCppCodeCounterParseFile(t *testing.T) {
 		return
 	}
 }
+
+func TestCppCodeCounterParseFileRawString(t *testing.T) {
+	filename := os.Args[len(os.Args)-1] + "\\src\\testdata\\test2.cpp"
+
+	counter, _ := NewCodeCounterFactory().NewCounter("cpp")
+	wanted := CodeStat{Total: 15, Code: 11, Comment: 4, Blank: 2}
+
+	stat, err := ParseFile(counter, filename)
+	if err != nil {
+		t.Errorf("TestCppCodeCounterParseFileRawString failed, ParseFile failed: %v", err)
+		return
+	}
+
+	if stat != wanted {
+		t.Errorf("TestCppCodeCounterParseFileRawString failed, stat = %s, wanted = %s", stat.String(), wanted.String())
+		return
+	}
+}
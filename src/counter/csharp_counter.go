@@ -0,0 +1,292 @@
+package counter
+
+import (
+	//"fmt"
+	"strings"
+)
+
+const (
+	CSHARP_CODE_COUNT_STATE_INIT            = 0
+	CSHARP_CODE_COUNT_STATE_LINE_COMMENT    = 1
+	CSHARP_CODE_COUNT_STATE_BLOCK_COMMENT   = 2
+	CSHARP_CODE_COUNT_STATE_CODE            = 3
+	CSHARP_CODE_COUNT_STATE_STRING          = 4
+	CSHARP_CODE_COUNT_STATE_STRING_ESCAPE   = 5
+	CSHARP_CODE_COUNT_STATE_VERBATIM_STRING = 6
+	CSHARP_CODE_COUNT_STATE_RAW_STRING      = 7
+	CSHARP_CODE_COUNT_STATE_CHAR            = 8
+	CSHARP_CODE_COUNT_STATE_CHAR_ESCAPE     = 9
+)
+
+// csharpString is a string literal, saved while counting the code inside
+// one of its {...} interpolation holes.
+type csharpString struct {
+	state   int
+	quotes  int // number of '"' closing a raw string
+	dollars int // number of '$', 0 for a string without interpolation
+	braces  int // nesting of '{' in the enclosing hole
+}
+
+type CSharpCodeCounter struct {
+	state   int
+	quotes  int
+	dollars int
+	braces  int
+	holes   []csharpString
+	doc     bool // current block comment is a documentation comment
+}
+
+func NewCSharpCodeCounter() *CSharpCodeCounter {
+	return &CSharpCodeCounter{}
+}
+
+func (c *CSharpCodeCounter) Clear() {
+	c.state = CSHARP_CODE_COUNT_STATE_INIT
+	c.quotes = 0
+	c.dollars = 0
+	c.braces = 0
+	c.holes = c.holes[:0]
+}
+
+func (c *CSharpCodeCounter) ParseLine(line string) (stat CodeStat) {
+	stat.Total = 1
+	line = strings.TrimSpace(line)
+
+	if len(line) == 0 {
+		switch c.state {
+		case CSHARP_CODE_COUNT_STATE_BLOCK_COMMENT:
+			stat.Comment = 1
+			if c.doc {
+				stat.Doc = 1
+			}
+		case CSHARP_CODE_COUNT_STATE_VERBATIM_STRING, CSHARP_CODE_COUNT_STATE_RAW_STRING:
+			stat.Code = 1
+		default:
+			stat.Blank = 1
+		}
+		return stat
+	}
+
+	hasCode := false
+	hasComment := false
+	hasDoc := false
+
+	for i := 0; i < len(line); i++ {
+		v := line[i]
+
+		if c.state == CSHARP_CODE_COUNT_STATE_LINE_COMMENT {
+			break
+		}
+
+		switch c.state {
+		case CSHARP_CODE_COUNT_STATE_INIT, CSHARP_CODE_COUNT_STATE_CODE:
+			switch v {
+			case ' ', '\t':
+				c.state = CSHARP_CODE_COUNT_STATE_INIT
+				continue
+			case '/':
+				switch peekByte(line, i+1) {
+				case '/':
+					// "///" starts an xml doc comment
+					c.state = CSHARP_CODE_COUNT_STATE_LINE_COMMENT
+					hasComment = true
+					hasDoc = isDocComment(line[i+2:], '/')
+					continue
+				case '*':
+					c.state = CSHARP_CODE_COUNT_STATE_BLOCK_COMMENT
+					c.doc = isDocComment(line[i+2:], '*')
+					hasComment = true
+					hasDoc = hasDoc || c.doc
+					i++
+					continue
+				}
+				c.state = CSHARP_CODE_COUNT_STATE_CODE
+			case '$', '@', '"':
+				i = c.startString(line, i)
+			case '\'':
+				c.state = CSHARP_CODE_COUNT_STATE_CHAR
+			case '{':
+				c.state = CSHARP_CODE_COUNT_STATE_CODE
+				c.braces++
+			case '}':
+				c.state = CSHARP_CODE_COUNT_STATE_CODE
+				if c.braces == 0 && len(c.holes) > 0 {
+					i = c.endHole(line, i)
+				} else if c.braces > 0 {
+					c.braces--
+				}
+			default:
+				c.state = CSHARP_CODE_COUNT_STATE_CODE
+			}
+			hasCode = true
+
+		case CSHARP_CODE_COUNT_STATE_BLOCK_COMMENT:
+			hasComment = true
+			hasDoc = hasDoc || c.doc
+			if v == '*' && peekByte(line, i+1) == '/' {
+				c.state = CSHARP_CODE_COUNT_STATE_INIT
+				i++
+			}
+
+		case CSHARP_CODE_COUNT_STATE_STRING:
+			hasCode = true
+			switch v {
+			case '\\':
+				c.state = CSHARP_CODE_COUNT_STATE_STRING_ESCAPE
+			case '"':
+				c.state = CSHARP_CODE_COUNT_STATE_CODE
+			case '{':
+				i = c.startHole(line, i)
+			}
+
+		case CSHARP_CODE_COUNT_STATE_STRING_ESCAPE:
+			c.state = CSHARP_CODE_COUNT_STATE_STRING
+
+		case CSHARP_CODE_COUNT_STATE_VERBATIM_STRING:
+			hasCode = true
+			switch v {
+			case '"':
+				if peekByte(line, i+1) == '"' {
+					// "" is an escaped quote
+					i++
+				} else {
+					c.state = CSHARP_CODE_COUNT_STATE_CODE
+				}
+			case '{':
+				i = c.startHole(line, i)
+			}
+
+		case CSHARP_CODE_COUNT_STATE_RAW_STRING:
+			hasCode = true
+			switch v {
+			case '"':
+				n := countByte(line, i, '"')
+				if n >= c.quotes {
+					c.state = CSHARP_CODE_COUNT_STATE_CODE
+				}
+				i += n - 1
+			case '{':
+				i = c.startHole(line, i)
+			}
+
+		case CSHARP_CODE_COUNT_STATE_CHAR:
+			hasCode = true
+			switch v {
+			case '\\':
+				c.state = CSHARP_CODE_COUNT_STATE_CHAR_ESCAPE
+			case '\'':
+				c.state = CSHARP_CODE_COUNT_STATE_CODE
+			}
+
+		case CSHARP_CODE_COUNT_STATE_CHAR_ESCAPE:
+			c.state = CSHARP_CODE_COUNT_STATE_CHAR
+		}
+	}
+
+	if hasCode {
+		stat.Code = 1
+	}
+
+	if hasComment {
+		stat.Comment = 1
+	}
+
+	if hasDoc {
+		stat.Doc = 1
+	}
+
+	switch c.state {
+	case CSHARP_CODE_COUNT_STATE_BLOCK_COMMENT:
+		break
+	case CSHARP_CODE_COUNT_STATE_VERBATIM_STRING:
+		break
+	case CSHARP_CODE_COUNT_STATE_RAW_STRING:
+		break
+	default:
+		c.state = CSHARP_CODE_COUNT_STATE_INIT
+	}
+
+	return stat
+}
+
+// startString handles the string literal prefix at line[i], which is any
+// mix of '$' and '@' followed by one or more '"', and returns the index of
+// its last byte. Without a following '"' the prefix is plain code.
+func (c *CSharpCodeCounter) startString(line string, i int) int {
+	dollars := 0
+	verbatim := false
+	j := i
+	for ; j < len(line); j++ {
+		if line[j] == '$' {
+			dollars++
+		} else if line[j] == '@' {
+			verbatim = true
+		} else {
+			break
+		}
+	}
+
+	if peekByte(line, j) != '"' {
+		c.state = CSHARP_CODE_COUNT_STATE_CODE
+		return j - 1
+	}
+
+	c.dollars = dollars
+	quotes := countByte(line, j, '"')
+	switch {
+	case quotes >= 3:
+		c.state = CSHARP_CODE_COUNT_STATE_RAW_STRING
+		c.quotes = quotes
+		return j + quotes - 1
+	case verbatim:
+		c.state = CSHARP_CODE_COUNT_STATE_VERBATIM_STRING
+	default:
+		c.state = CSHARP_CODE_COUNT_STATE_STRING
+	}
+	return j
+}
+
+// startHole handles a '{' at line[i] inside a string, which either starts
+// an interpolation hole or is literal text, and returns the index of the
+// last byte consumed.
+func (c *CSharpCodeCounter) startHole(line string, i int) int {
+	if c.dollars == 0 {
+		return i
+	}
+
+	n := countByte(line, i, '{')
+	if c.state != CSHARP_CODE_COUNT_STATE_RAW_STRING {
+		if n >= 2 {
+			// "{{" is an escaped brace
+			return i + 1
+		}
+	} else if n < c.dollars {
+		return i + n - 1
+	}
+
+	c.holes = append(c.holes, csharpString{state: c.state, quotes: c.quotes, dollars: c.dollars, braces: c.braces})
+	c.state = CSHARP_CODE_COUNT_STATE_CODE
+	c.braces = 0
+	return i + n - 1
+}
+
+// endHole handles the '}' at line[i] closing an interpolation hole, and
+// returns the index of the last byte consumed.
+func (c *CSharpCodeCounter) endHole(line string, i int) int {
+	s := c.holes[len(c.holes)-1]
+	c.holes = c.holes[:len(c.holes)-1]
+
+	c.state = s.state
+	c.quotes = s.quotes
+	c.dollars = s.dollars
+	c.braces = s.braces
+
+	if c.state == CSHARP_CODE_COUNT_STATE_RAW_STRING {
+		n := countByte(line, i, '}')
+		if n > c.dollars {
+			n = c.dollars
+		}
+		return i + n - 1
+	}
+	return i
+}
@@ -0,0 +1,62 @@
+package counter
+
+import (
+	//"fmt"
+	"os"
+	//"path/filepath"
+	"testing"
+)
+
+func TestCSharpCodeCounterParseLine(t *testing.T) {
+	testdata := []struct {
+		line string
+		stat CodeStat
+	}{
+		{" \t", CodeStat{Total: 1, Blank: 1}},
+		{"ab/c", CodeStat{Total: 1, Code: 1}},
+		{"ab//", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"/// <summary>", CodeStat{Total: 1, Comment: 1, Doc: 1}},
+		{"//// banner", CodeStat{Total: 1, Comment: 1}},
+		{"/** doc */", CodeStat{Total: 1, Comment: 1, Doc: 1}},
+		{"ab/*tt**/ cc ", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"@\"c:\\\" // c", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"@\"\"\"//\"", CodeStat{Total: 1, Code: 1}},
+		{"$\"{a}//\"", CodeStat{Total: 1, Code: 1}},
+		{"$\"{{//}}\"", CodeStat{Total: 1, Code: 1}},
+		{"$\"{f(\"}//\")}\" /* c */", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"$@\"{a}\\\" // c", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"\"\"\" \"\" // \"\"\"", CodeStat{Total: 1, Code: 1}},
+		{"$$\"\"\"{{a}} {//} \"\"\" // c", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"'\\'' // c", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"@class // c", CodeStat{Total: 1, Code: 1, Comment: 1}},
+	}
+
+	for i, v := range testdata {
+		counter, _ := NewCodeCounterFactory().NewCounter("csharp")
+
+		stat := counter.ParseLine(v.line)
+
+		if stat != v.stat {
+			t.Errorf("TestCSharpCodeCounterParseLine[%d] failed, stat = %s, wanted = %s", i, stat.String(), v.stat.String())
+			continue
+		}
+	}
+}
+
+func TestCSharpCodeCounterParseFile(t *testing.T) {
+	filename := os.Args[len(os.Args)-1] + "\\src\\testdata\\test1.cs"
+
+	counter, _ := NewCodeCounterFactory().NewCounter("csharp")
+	wanted := CodeStat{Total: 18, Code: 13, Comment: 5, Doc: 3, Blank: 1}
+
+	stat, err := ParseFile(counter, filename)
+	if err != nil {
+		t.Errorf("TestCSharpCodeCounterParseFile failed, ParseFile failed: %v", err)
+		return
+	}
+
+	if stat != wanted {
+		t.Errorf("TestCSharpCodeCounterParseFile failed, stat = %s, wanted = %s", stat.String(), wanted.String())
+		return
+	}
+}
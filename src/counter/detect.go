@@ -0,0 +1,192 @@
+package counter
+
+import (
+	//"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// reasons of a detected language
+const (
+	DETECT_REASON_EXTENSION = "extension"
+	DETECT_REASON_FILENAME  = "filename"
+	DETECT_REASON_SHEBANG   = "shebang"
+	DETECT_REASON_MODELINE  = "modeline"
+	DETECT_REASON_HEURISTIC = "heuristic"
+)
+
+// number of lines read from the head of a file to detect its language and
+// whether it is generated
+const DETECT_HEAD_LINES = 64
+
+// languages of interpreters in shebang lines, without version suffixes
+var shebangLanguages = map[string]string{
+	"sh":      "shell",
+	"bash":    "shell",
+	"zsh":     "shell",
+	"ksh":     "shell",
+	"dash":    "shell",
+	"ash":     "shell",
+	"python":  "python",
+	"pypy":    "python",
+	"ruby":    "ruby",
+	"perl":    "perl",
+	"lua":     "lua",
+	"node":    "javascript",
+	"nodejs":  "javascript",
+	"ts-node": "typescript",
+	"escript": "erlang",
+	"php":     "php",
+	"make":    "makefile",
+}
+
+// languages of emacs modes and vim filetypes
+var modelineLanguages = map[string]string{
+	"c":            "c",
+	"c++":          "cpp",
+	"cpp":          "cpp",
+	"objc":         "objc",
+	"objective-c":  "objc",
+	"objcpp":       "objc",
+	"go":           "go",
+	"java":         "java",
+	"csharp":       "csharp",
+	"cs":           "csharp",
+	"erlang":       "erlang",
+	"python":       "python",
+	"rust":         "rust",
+	"javascript":   "javascript",
+	"js":           "javascript",
+	"typescript":   "typescript",
+	"pascal":       "pascal",
+	"delphi":       "pascal",
+	"sh":           "shell",
+	"shell-script": "shell",
+	"bash":         "shell",
+	"zsh":          "shell",
+	"ruby":         "ruby",
+	"perl":         "perl",
+	"cperl":        "perl",
+	"lua":          "lua",
+	"sql":          "sql",
+	"haskell":      "haskell",
+	"kotlin":       "kotlin",
+	"swift":        "swift",
+	"php":          "php",
+	"css":          "css",
+	"html":         "html",
+	"xml":          "html",
+	"makefile":     "makefile",
+	"make":         "makefile",
+	"cmake":        "cmake",
+	"dockerfile":   "dockerfile",
+	"yaml":         "yaml",
+	"toml":         "toml",
+}
+
+var (
+	emacsModeline = regexp.MustCompile(`-\*-(.*)-\*-`)
+	vimModeline   = regexp.MustCompile(`\b(?:vi|vim|ex):.*\b(?:ft|filetype|syntax)=([\w+-]+)`)
+)
+
+// contentHeuristic picks language for a file with an ambiguous extension if
+// any of its lines matches pattern.
+type contentHeuristic struct {
+	language string
+	pattern  *regexp.Regexp
+}
+
+// heuristics of ambiguous extensions, tried in order
+var contentHeuristics = map[string][]contentHeuristic{
+	"h": {
+		{"objc", regexp.MustCompile(`^\s*(@interface|@implementation|@protocol|@end|@property|@class|#import)\b`)},
+		{"cpp", regexp.MustCompile(`^\s*(class\s+\w+\s*([:{;]|$)|namespace\b|template\s*<|(public|private|protected)\s*:|using\s+namespace\b|#include\s*<\w+>)|\w::\w`)},
+	},
+}
+
+// DetectShebang returns the language of the interpreter in a "#!" line, like
+// "#!/usr/bin/env python3", or "" if it is unknown.
+func DetectShebang(line string) string {
+	if !strings.HasPrefix(line, "#!") {
+		return ""
+	}
+
+	fields := strings.Fields(line[2:])
+	if len(fields) == 0 {
+		return ""
+	}
+
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		// skip options and variables of env, like "env -S VAR=1 node"
+		interpreter = ""
+		for _, v := range fields[1:] {
+			if !strings.HasPrefix(v, "-") && !strings.Contains(v, "=") {
+				interpreter = path.Base(v)
+				break
+			}
+		}
+	}
+
+	// python3.11 is python
+	interpreter = strings.TrimRight(interpreter, "0123456789.")
+	return shebangLanguages[interpreter]
+}
+
+// DetectModeline returns the language of an emacs modeline like
+// "-*- mode: c++ -*-" or a vim modeline like "vim: set ft=python:" in lines,
+// or "" if there is none.
+func DetectModeline(lines []string) string {
+	for _, line := range lines {
+		if m := emacsModeline.FindStringSubmatch(line); m != nil {
+			if lang := emacsMode(m[1]); lang != "" {
+				return lang
+			}
+		}
+		if m := vimModeline.FindStringSubmatch(line); m != nil {
+			if lang, ok := modelineLanguages[strings.ToLower(m[1])]; ok {
+				return lang
+			}
+		}
+	}
+	return ""
+}
+
+// emacsMode returns the language of the variables of an emacs modeline,
+// which are either a single mode like "c++" or pairs like "mode: c++;".
+func emacsMode(vars string) string {
+	vars = strings.TrimSpace(vars)
+	if !strings.Contains(vars, ":") {
+		return modelineLanguages[strings.ToLower(vars)]
+	}
+
+	for _, v := range strings.Split(vars, ";") {
+		pair := strings.SplitN(v, ":", 2)
+		if len(pair) == 2 && strings.EqualFold(strings.TrimSpace(pair[0]), "mode") {
+			return modelineLanguages[strings.ToLower(strings.TrimSpace(pair[1]))]
+		}
+	}
+	return ""
+}
+
+// IsAmbiguousExtension reports whether files with the lowercase extension ext
+// may be of different languages, see DetectHeuristic.
+func IsAmbiguousExtension(ext string) bool {
+	_, ok := contentHeuristics[ext]
+	return ok
+}
+
+// DetectHeuristic guesses the language of a file with the ambiguous extension
+// ext from its lines, like "cpp" or "objc" for a .h file, or returns "" if
+// nothing matches.
+func DetectHeuristic(ext string, lines []string) string {
+	for _, h := range contentHeuristics[ext] {
+		for _, line := range lines {
+			if h.pattern.MatchString(line) {
+				return h.language
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,79 @@
+package counter
+
+import (
+	//"fmt"
+	"testing"
+)
+
+func TestDetectShebang(t *testing.T) {
+	testdata := []struct {
+		line string
+		lang string
+	}{
+		{"#!/bin/sh", "shell"},
+		{"#! /bin/bash -e", "shell"},
+		{"#!/usr/bin/env python3", "python"},
+		{"#!/usr/bin/python3.11", "python"},
+		{"#!/usr/bin/env -S VAR=1 node --harmony", "javascript"},
+		{"#!/usr/bin/env escript", "erlang"},
+		{"#!/usr/bin/make -f", "makefile"},
+		{"#!/usr/bin/env", ""},
+		{"#!/usr/bin/unknown", ""},
+		{"# not a shebang", ""},
+	}
+
+	for i, v := range testdata {
+		lang := DetectShebang(v.line)
+		if lang != v.lang {
+			t.Errorf("TestDetectShebang[%d] failed, lang = %s, wanted = %s", i, lang, v.lang)
+		}
+	}
+}
+
+func TestDetectModeline(t *testing.T) {
+	testdata := []struct {
+		lines []string
+		lang  string
+	}{
+		{[]string{"/* -*- C++ -*- */"}, "cpp"},
+		{[]string{"#!/bin/sh", "# -*- mode: python; coding: utf-8 -*-"}, "python"},
+		{[]string{"// -*- coding: utf-8; mode: objc -*-"}, "objc"},
+		{[]string{"// vim: set ft=cpp :"}, "cpp"},
+		{[]string{"# vi: sw=4 filetype=ruby"}, "ruby"},
+		{[]string{"# vim: set ts=4 :"}, ""},
+		{[]string{"-*- unknown -*-"}, ""},
+		{[]string{"int a;"}, ""},
+	}
+
+	for i, v := range testdata {
+		lang := DetectModeline(v.lines)
+		if lang != v.lang {
+			t.Errorf("TestDetectModeline[%d] failed, lang = %s, wanted = %s", i, lang, v.lang)
+		}
+	}
+}
+
+func TestDetectHeuristic(t *testing.T) {
+	testdata := []struct {
+		ext   string
+		lines []string
+		lang  string
+	}{
+		{"h", []string{"#include <stdio.h>", "int f(void);"}, ""},
+		{"h", []string{"#include <vector>"}, "cpp"},
+		{"h", []string{"namespace a {"}, "cpp"},
+		{"h", []string{"class A : public B"}, "cpp"},
+		{"h", []string{"template <typename T>"}, "cpp"},
+		{"h", []string{"std::string f();"}, "cpp"},
+		{"h", []string{"#import <Foundation/Foundation.h>"}, "objc"},
+		{"h", []string{"@interface A : NSObject", "public:"}, "objc"},
+		{"c", []string{"namespace a {"}, ""},
+	}
+
+	for i, v := range testdata {
+		lang := DetectHeuristic(v.ext, v.lines)
+		if lang != v.lang {
+			t.Errorf("TestDetectHeuristic[%d] failed, lang = %s, wanted = %s", i, lang, v.lang)
+		}
+	}
+}
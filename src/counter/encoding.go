@@ -0,0 +1,214 @@
+package counter
+
+import (
+	"bufio"
+	"bytes"
+	//"fmt"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// encodings of text files
+const (
+	ENCODING_UTF8     = "utf-8"
+	ENCODING_UTF8_BOM = "utf-8-bom"
+	ENCODING_UTF16LE  = "utf-16le"
+	ENCODING_UTF16BE  = "utf-16be"
+	ENCODING_LATIN1   = "latin-1"
+	ENCODING_BINARY   = "binary" // not text
+)
+
+// text with more control characters in percent than this is binary
+const BINARY_CONTROL_PERCENT = 10
+
+// number of bytes at the head of a file used to detect its encoding
+const ENCODING_SNIFF_SIZE = 1024
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+// DetectEncoding returns the encoding of a file from head, its first
+// ENCODING_SNIFF_SIZE bytes or all of a shorter file, and the length of its
+// byte order mark. Without a BOM, text with a zero in most odd or even bytes
+// is UTF-16, other content with a zero or many control characters is binary,
+// and text which is not valid UTF-8 is Latin-1.
+func DetectEncoding(head []byte) (encoding string, bomLen int) {
+	switch {
+	case bytes.HasPrefix(head, bomUTF8):
+		return ENCODING_UTF8_BOM, len(bomUTF8)
+	case bytes.HasPrefix(head, bomUTF16LE):
+		return ENCODING_UTF16LE, len(bomUTF16LE)
+	case bytes.HasPrefix(head, bomUTF16BE):
+		return ENCODING_UTF16BE, len(bomUTF16BE)
+	}
+
+	// most code is ascii, so the other byte of most UTF-16 units is zero
+	units := len(head) / 2
+	zeros := [2]int{}
+	for i := 0; i+1 < len(head); i += 2 {
+		if head[i] == 0 {
+			zeros[0]++
+		}
+		if head[i+1] == 0 {
+			zeros[1]++
+		}
+	}
+	if units > 0 {
+		if zeros[1]*10 >= units*3 && zeros[0]*20 <= units {
+			return ENCODING_UTF16LE, 0
+		}
+		if zeros[0]*10 >= units*3 && zeros[1]*20 <= units {
+			return ENCODING_UTF16BE, 0
+		}
+	}
+
+	// a full head may end in the middle of a character
+	valid := isUTF8Prefix(head, len(head) >= ENCODING_SNIFF_SIZE)
+	if bytes.IndexByte(head, 0) >= 0 || isBinary(head, valid) {
+		return ENCODING_BINARY, 0
+	}
+	if !valid {
+		return ENCODING_LATIN1, 0
+	}
+	return ENCODING_UTF8, 0
+}
+
+// isBinary reports whether head has too many control characters for text,
+// counting the C1 controls which are not printable in Latin-1 too.
+func isBinary(head []byte, valid bool) bool {
+	controls := 0
+	for _, v := range head {
+		switch {
+		case v == '\t' || v == '\n' || v == '\r' || v == '\f' || v == '\v' || v == 0x1b:
+		case v < 0x20 || v == 0x7f:
+			controls++
+		case !valid && v >= 0x80 && v < 0xa0:
+			controls++
+		}
+	}
+	return controls*100 > len(head)*BINARY_CONTROL_PERCENT
+}
+
+// isUTF8Prefix reports whether b is valid UTF-8, except for an incomplete
+// character at its end if truncated is set.
+func isUTF8Prefix(b []byte, truncated bool) bool {
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size == 1 {
+			return truncated && !utf8.FullRune(b)
+		}
+		b = b[size:]
+	}
+	return true
+}
+
+// DecodeReader returns a reader of the text of r as UTF-8, without byte
+// order mark, and the encoding of r detected by DetectEncoding. Binary
+// content is read as it is.
+func DecodeReader(r io.Reader) (decoded io.Reader, encoding string, err error) {
+	reader := bufio.NewReaderSize(r, ENCODING_SNIFF_SIZE)
+	head, err := reader.Peek(ENCODING_SNIFF_SIZE)
+	if err != nil && err != io.EOF {
+		return nil, "", err
+	}
+
+	encoding, bomLen := DetectEncoding(head)
+	reader.Discard(bomLen)
+
+	switch encoding {
+	case ENCODING_UTF16LE:
+		return newDecodingReader(reader, decodeUTF16LE), encoding, nil
+	case ENCODING_UTF16BE:
+		return newDecodingReader(reader, decodeUTF16BE), encoding, nil
+	case ENCODING_LATIN1:
+		return newDecodingReader(reader, decodeLatin1), encoding, nil
+	}
+	return reader, encoding, nil
+}
+
+// decodeFunc appends the UTF-8 of the complete characters at the head of src
+// to dst, and returns the number of bytes of src decoded. At the end of the
+// text, atEOF is set and all of src must be decoded.
+type decodeFunc func(dst, src []byte, atEOF bool) ([]byte, int)
+
+// decodingReader reads the text of r decoded to UTF-8 by decode.
+type decodingReader struct {
+	r      io.Reader
+	decode decodeFunc
+	chunk  []byte // buffer to read r
+	in     []byte // bytes read from r but not decoded yet
+	out    []byte // decoded bytes not read yet
+	err    error  // error of r
+}
+
+func newDecodingReader(r io.Reader, decode decodeFunc) *decodingReader {
+	return &decodingReader{r: r, decode: decode, chunk: make([]byte, 4096)}
+}
+
+func (d *decodingReader) Read(p []byte) (int, error) {
+	for len(d.out) == 0 && d.err == nil {
+		n, err := d.r.Read(d.chunk)
+		d.in = append(d.in, d.chunk[:n]...)
+		d.err = err
+
+		var used int
+		d.out, used = d.decode(d.out[:0], d.in, err != nil)
+		d.in = append(d.in[:0], d.in[used:]...)
+	}
+
+	if len(d.out) == 0 {
+		return 0, d.err
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+func decodeUTF16LE(dst, src []byte, atEOF bool) ([]byte, int) {
+	return decodeUTF16(dst, src, atEOF, func(b []byte) rune { return rune(b[0]) | rune(b[1])<<8 })
+}
+
+func decodeUTF16BE(dst, src []byte, atEOF bool) ([]byte, int) {
+	return decodeUTF16(dst, src, atEOF, func(b []byte) rune { return rune(b[0])<<8 | rune(b[1]) })
+}
+
+func decodeUTF16(dst, src []byte, atEOF bool, unit func([]byte) rune) ([]byte, int) {
+	i := 0
+	for ; i+1 < len(src); i += 2 {
+		r := unit(src[i:])
+		if r >= 0xd800 && r < 0xdc00 {
+			// a high surrogate needs the low surrogate after it
+			if i+3 >= len(src) {
+				if !atEOF {
+					break
+				}
+				dst = utf8.AppendRune(dst, utf8.RuneError)
+				continue
+			}
+			if pair := utf16.DecodeRune(r, unit(src[i+2:])); pair != utf8.RuneError {
+				r = pair
+				i += 2
+			}
+		}
+		// AppendRune writes a lone surrogate as utf8.RuneError
+		dst = utf8.AppendRune(dst, r)
+	}
+
+	if atEOF && i < len(src) {
+		// a half unit at the end
+		dst = utf8.AppendRune(dst, utf8.RuneError)
+		i = len(src)
+	}
+	return dst, i
+}
+
+func decodeLatin1(dst, src []byte, atEOF bool) ([]byte, int) {
+	for _, v := range src {
+		dst = utf8.AppendRune(dst, rune(v))
+	}
+	return dst, len(src)
+}
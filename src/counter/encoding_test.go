@@ -0,0 +1,116 @@
+package counter
+
+import (
+	//"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf16"
+)
+
+func encodeUTF16(s string, bigEndian bool, bom bool) string {
+	units := utf16.Encode([]rune(s))
+	if bom {
+		units = append([]uint16{0xfeff}, units...)
+	}
+
+	b := make([]byte, 0, 2*len(units))
+	for _, v := range units {
+		if bigEndian {
+			b = append(b, byte(v>>8), byte(v))
+		} else {
+			b = append(b, byte(v), byte(v>>8))
+		}
+	}
+	return string(b)
+}
+
+func TestDetectEncoding(t *testing.T) {
+	testdata := []struct {
+		head     string
+		encoding string
+		bomLen   int
+	}{
+		{"", ENCODING_UTF8, 0},
+		{"int a;", ENCODING_UTF8, 0},
+		{"a = \"\xc3\xa9\"", ENCODING_UTF8, 0},
+		{"a = \"\xc3", ENCODING_LATIN1, 0},
+		{strings.Repeat("a", ENCODING_SNIFF_SIZE-1) + "\xc3", ENCODING_UTF8, 0},
+		{"\xef\xbb\xbfint a;", ENCODING_UTF8_BOM, 3},
+		{encodeUTF16("int a;", false, true), ENCODING_UTF16LE, 2},
+		{encodeUTF16("int a;", true, true), ENCODING_UTF16BE, 2},
+		{encodeUTF16("int a; // 中文", false, false), ENCODING_UTF16LE, 0},
+		{encodeUTF16("int a; // 中文", true, false), ENCODING_UTF16BE, 0},
+		{"a = \"\xe9t\xe9\"", ENCODING_LATIN1, 0},
+		{"\x7fELF\x02\x01\x01\x00\x00", ENCODING_BINARY, 0},
+		{"GIF89a\x01\x02\x03\x04\x05", ENCODING_BINARY, 0},
+		{"a\x01b\x02c\x03d\x04e\x05", ENCODING_BINARY, 0},
+		{"caf\xe9 \x81\x82\x83\x84", ENCODING_BINARY, 0},
+		{"\x1b[31mred\x1b[0m\n\f", ENCODING_UTF8, 0},
+	}
+
+	for i, v := range testdata {
+		encoding, bomLen := DetectEncoding([]byte(v.head))
+		if encoding != v.encoding || bomLen != v.bomLen {
+			t.Errorf("TestDetectEncoding[%d] failed, encoding = %s, bomLen = %d, wanted = %s, %d", i, encoding, bomLen, v.encoding, v.bomLen)
+		}
+	}
+}
+
+func TestDecodeReader(t *testing.T) {
+	text := "int a; // é\U0001f600\r\n/* b */\n"
+	long := strings.Repeat("x = 1;\n", 2000)
+
+	testdata := []struct {
+		content  string
+		encoding string
+		text     string
+	}{
+		{text, ENCODING_UTF8, text},
+		{"\xef\xbb\xbf" + text, ENCODING_UTF8_BOM, text},
+		{encodeUTF16(text, false, true), ENCODING_UTF16LE, text},
+		{encodeUTF16(text, true, true), ENCODING_UTF16BE, text},
+		{encodeUTF16(text, false, false), ENCODING_UTF16LE, text},
+		{encodeUTF16(long+text, false, true), ENCODING_UTF16LE, long + text},
+		{encodeUTF16("a", false, true) + "\x00", ENCODING_UTF16LE, "a�"},
+		{"caf\xe9", ENCODING_LATIN1, "café"},
+	}
+
+	for i, v := range testdata {
+		// read one byte at a time to split surrogates and characters
+		decoded, encoding, err := DecodeReader(iotest.OneByteReader(strings.NewReader(v.content)))
+		if err != nil {
+			t.Errorf("TestDecodeReader[%d] failed, err = %v", i, err)
+			continue
+		}
+
+		b, err := io.ReadAll(decoded)
+		if err != nil {
+			t.Errorf("TestDecodeReader[%d] failed, err = %v", i, err)
+			continue
+		}
+
+		if encoding != v.encoding || string(b) != v.text {
+			t.Errorf("TestDecodeReader[%d] failed, encoding = %s, text = %q, wanted = %s, %q", i, encoding, b, v.encoding, v.text)
+		}
+	}
+}
+
+func TestParseReaderUTF16(t *testing.T) {
+	content := encodeUTF16("// a\r\nint a;\r\n\r\n/* b\r\n */", false, true)
+
+	decoded, _, _ := DecodeReader(strings.NewReader(content))
+	counter, _ := NewCodeCounterFactory().NewCounter("cpp")
+	wanted := CodeStat{Total: 5, Code: 1, Comment: 3, Blank: 1}
+
+	stat, err := ParseReader(counter, decoded)
+	if err != nil {
+		t.Errorf("TestParseReaderUTF16 failed, err = %v", err)
+		return
+	}
+
+	if stat != wanted {
+		t.Errorf("TestParseReaderUTF16 failed, stat = %s, wanted = %s", stat.String(), wanted.String())
+	}
+}
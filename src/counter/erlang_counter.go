@@ -6,40 +6,81 @@ import (
 )
 
 const (
-	ERLANG_CODE_COUNT_STATE_INIT         = 0
-	ERLANG_CODE_COUNT_STATE_LINE_COMMENT = 1
-	ERLANG_CODE_COUNT_STATE_CODE         = 2
-	ERLANG_CODE_COUNT_STATE_STRING       = 3
-	ERLANG_CODE_COUNT_STATE_ATOM         = 4
+	ERLANG_CODE_COUNT_STATE_INIT          = 0
+	ERLANG_CODE_COUNT_STATE_LINE_COMMENT  = 1
+	ERLANG_CODE_COUNT_STATE_CODE          = 2
+	ERLANG_CODE_COUNT_STATE_STRING        = 3
+	ERLANG_CODE_COUNT_STATE_ATOM          = 4
+	ERLANG_CODE_COUNT_STATE_STRING_ESCAPE = 5
+	ERLANG_CODE_COUNT_STATE_ATOM_ESCAPE   = 6
+	ERLANG_CODE_COUNT_STATE_CHAR          = 7
+	ERLANG_CODE_COUNT_STATE_CHAR_ESCAPE   = 8
+	ERLANG_CODE_COUNT_STATE_TRIPLE_STRING = 9
+	ERLANG_CODE_COUNT_STATE_SIGIL         = 10
+	ERLANG_CODE_COUNT_STATE_SIGIL_STRING  = 11
+	ERLANG_CODE_COUNT_STATE_SIGIL_ESCAPE  = 12
 )
 
+// closing delimiters of sigil strings
+var erlangSigilDelimiters = map[byte]byte{
+	'(': ')', '[': ']', '{': '}', '<': '>',
+	'/': '/', '|': '|', '\'': '\'', '"': '"', '`': '`', '#': '#',
+}
+
 type ErlangCodeCounter struct {
-	state int
+	state    int
+	quotes   int  // number of '"' closing a triple-quoted string
+	close    byte // closing delimiter of a sigil string
+	verbatim bool // sigil string without escapes, like ~S"..."
+	edoc     bool // in a comment block starting with "%% @doc"
+	docAttr  bool // in a -doc or -moduledoc attribute
 }
 
 func NewErlangCodeCounter() *ErlangCodeCounter {
 	return &ErlangCodeCounter{}
 }
 
-func (c *ErlangCodeCounter) Clear() { c.state = ERLANG_CODE_COUNT_STATE_INIT }
+func (c *ErlangCodeCounter) Clear() {
+	c.state = ERLANG_CODE_COUNT_STATE_INIT
+	c.quotes = 0
+	c.close = 0
+	c.verbatim = false
+	c.edoc = false
+	c.docAttr = false
+}
 
 func (c *ErlangCodeCounter) ParseLine(line string) (stat CodeStat) {
 	stat.Total = 1
 	line = strings.TrimSpace(line)
 
 	if len(line) == 0 {
-		stat.Blank = 1
+		switch {
+		case c.docAttr:
+			stat.Comment = 1
+			stat.Doc = 1
+		case c.state == ERLANG_CODE_COUNT_STATE_TRIPLE_STRING:
+			stat.Code = 1
+		default:
+			stat.Blank = 1
+			c.edoc = false
+		}
 		return stat
 	}
 
 	hasCode := false
 	hasComment := false
+	hasDoc := false
+
+	if c.state == ERLANG_CODE_COUNT_STATE_INIT && (strings.HasPrefix(line, "-doc") || strings.HasPrefix(line, "-moduledoc")) {
+		c.docAttr = true
+	}
 
 	/*if c.state != ERLANG_CODE_COUNT_STATE_STRING && c.state != ERLANG_CODE_COUNT_STATE_ATOM {
 		c.state = ERLANG_CODE_COUNT_STATE_INIT
 	}*/
 
-	for _, v := range line {
+	for i := 0; i < len(line); i++ {
+		v := line[i]
 		//fmt.Printf("v = %c, state = %d\n", v, c.state)
 
 		if c.state == ERLANG_CODE_COUNT_STATE_LINE_COMMENT {
@@ -47,53 +88,124 @@ func (c *ErlangCodeCounter) ParseLine(line string) (stat CodeStat) {
 		}
 
 		switch c.state {
-		case ERLANG_CODE_COUNT_STATE_INIT:
+		case ERLANG_CODE_COUNT_STATE_INIT, ERLANG_CODE_COUNT_STATE_CODE:
 			switch v {
 			case '%':
 				c.state = ERLANG_CODE_COUNT_STATE_LINE_COMMENT
 				hasComment = true
+				if !hasCode && strings.HasPrefix(strings.TrimLeft(line[i:], "% \t"), "@doc") {
+					c.edoc = true
+				}
+				hasDoc = !hasCode && c.edoc
+				continue
 			case '"':
-				c.state = ERLANG_CODE_COUNT_STATE_STRING
-				hasCode = true
+				if n := countByte(line, i, '"'); n >= 3 {
+					c.state = ERLANG_CODE_COUNT_STATE_TRIPLE_STRING
+					c.quotes = n
+					i += n - 1
+				} else {
+					c.state = ERLANG_CODE_COUNT_STATE_STRING
+				}
 			case '\'':
 				c.state = ERLANG_CODE_COUNT_STATE_ATOM
-				hasCode = true
+			case '$':
+				c.state = ERLANG_CODE_COUNT_STATE_CHAR
+			case '~':
+				c.state = ERLANG_CODE_COUNT_STATE_SIGIL
+				c.verbatim = false
 			default:
 				c.state = ERLANG_CODE_COUNT_STATE_CODE
-				hasCode = true
 			}
+			hasCode = true
 
-		case ERLANG_CODE_COUNT_STATE_CODE:
+		case ERLANG_CODE_COUNT_STATE_STRING:
+			hasCode = true
 			switch v {
-			case '%':
-				c.state = ERLANG_CODE_COUNT_STATE_LINE_COMMENT
-				hasComment = true
+			case '\\':
+				c.state = ERLANG_CODE_COUNT_STATE_STRING_ESCAPE
 			case '"':
-				c.state = ERLANG_CODE_COUNT_STATE_STRING
-				hasCode = true
+				c.state = ERLANG_CODE_COUNT_STATE_CODE
+			}
+
+		case ERLANG_CODE_COUNT_STATE_STRING_ESCAPE:
+			c.state = ERLANG_CODE_COUNT_STATE_STRING
+
+		case ERLANG_CODE_COUNT_STATE_ATOM:
+			hasCode = true
+			switch v {
+			case '\\':
+				c.state = ERLANG_CODE_COUNT_STATE_ATOM_ESCAPE
 			case '\'':
-				c.state = ERLANG_CODE_COUNT_STATE_ATOM
-				hasCode = true
-			default:
 				c.state = ERLANG_CODE_COUNT_STATE_CODE
-				hasCode = true
 			}
 
-		case ERLANG_CODE_COUNT_STATE_STRING:
+		case ERLANG_CODE_COUNT_STATE_ATOM_ESCAPE:
+			c.state = ERLANG_CODE_COUNT_STATE_ATOM
+
+		case ERLANG_CODE_COUNT_STATE_CHAR:
+			// $% and $" are characters, not a comment or a string
 			hasCode = true
-			if v == '"' {
+			if v == '\\' {
+				c.state = ERLANG_CODE_COUNT_STATE_CHAR_ESCAPE
+			} else {
 				c.state = ERLANG_CODE_COUNT_STATE_CODE
 			}
 
-		case ERLANG_CODE_COUNT_STATE_ATOM:
+		case ERLANG_CODE_COUNT_STATE_CHAR_ESCAPE:
+			c.state = ERLANG_CODE_COUNT_STATE_CODE
+
+		case ERLANG_CODE_COUNT_STATE_TRIPLE_STRING:
+			// the closing quotes must be first on their line
 			hasCode = true
-			if v == '\'' {
+			if i == 0 && countByte(line, i, '"') >= c.quotes {
 				c.state = ERLANG_CODE_COUNT_STATE_CODE
+				i += c.quotes - 1
+			} else {
+				i = len(line)
 			}
 
+		case ERLANG_CODE_COUNT_STATE_SIGIL:
+			hasCode = true
+			if v == 'b' || v == 's' || v == 'B' || v == 'S' {
+				c.verbatim = v == 'B' || v == 'S'
+				break
+			}
+			if n := countByte(line, i, '"'); n >= 3 {
+				c.state = ERLANG_CODE_COUNT_STATE_TRIPLE_STRING
+				c.quotes = n
+				i += n - 1
+			} else if end, ok := erlangSigilDelimiters[v]; ok {
+				c.state = ERLANG_CODE_COUNT_STATE_SIGIL_STRING
+				c.close = end
+			} else {
+				c.state = ERLANG_CODE_COUNT_STATE_CODE
+			}
+
+		case ERLANG_CODE_COUNT_STATE_SIGIL_STRING:
+			hasCode = true
+			switch {
+			case v == '\\' && !c.verbatim:
+				c.state = ERLANG_CODE_COUNT_STATE_SIGIL_ESCAPE
+			case v == c.close:
+				c.state = ERLANG_CODE_COUNT_STATE_CODE
+			}
+
+		case ERLANG_CODE_COUNT_STATE_SIGIL_ESCAPE:
+			c.state = ERLANG_CODE_COUNT_STATE_SIGIL_STRING
 		}
 	}
 
+	if hasCode {
+		c.edoc = false
+	}
+
+	// the documentation in -doc attributes is counted like a comment
+	if c.docAttr {
+		hasCode = false
+		hasComment = true
+		hasDoc = true
+	}
+
 	if hasCode {
 		stat.Code = 1
 	}
@@ -102,13 +214,28 @@ func (c *ErlangCodeCounter) ParseLine(line string) (stat CodeStat) {
 		stat.Comment = 1
 	}
 
+	if hasDoc {
+		stat.Doc = 1
+	}
+
 	switch c.state {
 	case ERLANG_CODE_COUNT_STATE_STRING:
 		break
+	case ERLANG_CODE_COUNT_STATE_STRING_ESCAPE:
+		c.state = ERLANG_CODE_COUNT_STATE_STRING
 	case ERLANG_CODE_COUNT_STATE_ATOM:
 		break
+	case ERLANG_CODE_COUNT_STATE_ATOM_ESCAPE:
+		c.state = ERLANG_CODE_COUNT_STATE_ATOM
+	case ERLANG_CODE_COUNT_STATE_TRIPLE_STRING:
+		break
+	case ERLANG_CODE_COUNT_STATE_SIGIL_STRING:
+		break
+	case ERLANG_CODE_COUNT_STATE_SIGIL_ESCAPE:
+		c.state = ERLANG_CODE_COUNT_STATE_SIGIL_STRING
 	default:
 		c.state = ERLANG_CODE_COUNT_STATE_INIT
+		c.docAttr = false
 	}
 
 	return stat
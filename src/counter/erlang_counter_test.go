@@ -19,6 +19,16 @@ func TestErlangCodeCounterParseLine(t *testing.T) {
 		{" \"%\"\tcc\t ff\"\\t\" d", CodeStat{Total: 1, Code: 1, Comment: 0}},
 		{" '%'\tcc\t ff\"\\t\" d", CodeStat{Total: 1, Code: 1, Comment: 0}},
 		{" aa'%'\tcc\t ff\"\\t\" d", CodeStat{Total: 1, Code: 1, Comment: 0}},
+		{"X = $%, Y", CodeStat{Total: 1, Code: 1}},
+		{"X = $\", Y", CodeStat{Total: 1, Code: 1}},
+		{"X = $', Y", CodeStat{Total: 1, Code: 1}},
+		{"X = $\\%, Y % c", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"X = \"\\\"%\"", CodeStat{Total: 1, Code: 1}},
+		{"X = 'a\\'%'", CodeStat{Total: 1, Code: 1}},
+		{"X = ~\"%\"", CodeStat{Total: 1, Code: 1}},
+		{"X = ~s{\\}%}", CodeStat{Total: 1, Code: 1}},
+		{"X = ~S[\\] % c", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"X = ~B<%> % c", CodeStat{Total: 1, Code: 1, Comment: 1}},
 	}
 
 	for i, v := range testdata {
@@ -33,15 +43,47 @@ func TestErlangCodeCounterParseLine(t *testing.T) {
 	}
 }
 
+func TestErlangCodeCounterParseLines(t *testing.T) {
+	testdata := []struct {
+		lines []string
+		stat  CodeStat
+	}{
+		{[]string{"X = \"\"\"", "% a", "", "\" b", "\"\"\" % c"}, CodeStat{Total: 5, Code: 5, Comment: 1}},
+		{[]string{"X = \"\"\"\"", "\"\"\" a", "\"\"\"\". % c"}, CodeStat{Total: 3, Code: 3, Comment: 1}},
+		{[]string{"X = ~s\"\"\"", "% a", "\"\"\"", "% b"}, CodeStat{Total: 4, Code: 3, Comment: 1}},
+		{[]string{"X = ~s(", "% a", ")", "% b"}, CodeStat{Total: 4, Code: 3, Comment: 1}},
+		{[]string{"X = \"a\\", "% b\"", "% c"}, CodeStat{Total: 3, Code: 2, Comment: 1}},
+		{[]string{"%% @doc a", "%% b", "", "%% c"}, CodeStat{Total: 4, Comment: 3, Doc: 2, Blank: 1}},
+		{[]string{"%% a", "%% @doc b", "%% c", "f() -> ok. % d", "% e"}, CodeStat{Total: 5, Code: 1, Comment: 5, Doc: 2}},
+		{[]string{"-doc \"a\".", "f() -> ok."}, CodeStat{Total: 2, Code: 1, Comment: 1, Doc: 1}},
+		{[]string{"-moduledoc \"\"\"", "a", "", "\"\"\".", "f() -> ok."}, CodeStat{Total: 5, Code: 1, Comment: 4, Doc: 4}},
+	}
+
+	for i, v := range testdata {
+		counter, _ := NewCodeCounterFactory().NewCounter("erlang")
+
+		stat := CodeStat{}
+		for _, line := range v.lines {
+			lineStat := counter.ParseLine(line)
+			stat.Add(&lineStat)
+		}
+
+		if stat != v.stat {
+			t.Errorf("TestErlangCodeCounterParseLines[%d] failed, stat = %s, wanted = %s", i, stat.String(), v.stat.String())
+			continue
+		}
+	}
+}
+
 func TestErlangCodeCounterParseFile(t *testing.T) {
 	filename := os.Args[len(os.Args)-1] + "\\src\\testdata\\test1.erl"
 
 	counter, _ := NewCodeCounterFactory().NewCounter("erlang")
 	wanted := CodeStat{Total: 11, Code: 5, Comment: 4, Blank: 3}
 
-	stat, ok := ParseFile(counter, filename)
-	if !ok {
-		t.Errorf("TestErlangCodeCounterParseFile failed, ParseFile failed")
+	stat, err := ParseFile(counter, filename)
+	if err != nil {
+		t.Errorf("TestErlangCodeCounterParseFile failed, ParseFile failed: %v", err)
 		return
 	}
 
@@ -50,3 +92,21 @@ func TestErlangCodeCounterParseFile(t *testing.T) {
 		return
 	}
 }
+
+func TestErlangCodeCounterParseFileLiterals(t *testing.T) {
+	filename := os.Args[len(os.Args)-1] + "\\src\\testdata\\test2.erl"
+
+	counter, _ := NewCodeCounterFactory().NewCounter("erlang")
+	wanted := CodeStat{Total: 18, Code: 17, Comment: 2, Blank: 1}
+
+	stat, err := ParseFile(counter, filename)
+	if err != nil {
+		t.Errorf("TestErlangCodeCounterParseFileLiterals failed, ParseFile failed: %v", err)
+		return
+	}
+
+	if stat != wanted {
+		t.Errorf("TestErlangCodeCounterParseFileLiterals failed, stat = %s, wanted = %s", stat.String(), wanted.String())
+		return
+	}
+}
@@ -0,0 +1,57 @@
+package counter
+
+import (
+	//"fmt"
+	"regexp"
+)
+
+// start of a comment line, like "// ", "# " or " * " in a block comment
+const GENERATED_COMMENT_PREFIX = `^\s*(//|#|/?\*|--|;|<!--|\{-|\(\*|%)`
+
+// banners of generated files, the go convention first. Banners other than
+// the go one are found in any comment line, not in code like string literals.
+var defaultGeneratedPatterns = []string{
+	`^// Code generated .* DO NOT EDIT\.$`,
+	GENERATED_COMMENT_PREFIX + `.*Generated by the protocol buffer compiler\.\s+DO NOT EDIT!`,
+	GENERATED_COMMENT_PREFIX + `.*@generated\b`,
+	GENERATED_COMMENT_PREFIX + `(?i).*\b(auto-?generated|generated automatically|generated by)\b.*\bdo not (edit|modify)\b`,
+}
+
+// GeneratedDetector reports whether a file is the output of a code generator
+// from banner lines at its head, like "// Code generated by protoc-gen-go.
+// DO NOT EDIT." of go.
+type GeneratedDetector struct {
+	patterns []*regexp.Regexp
+}
+
+// NewGeneratedDetector returns a detector of the go convention and some well
+// known banners of other generators.
+func NewGeneratedDetector() *GeneratedDetector {
+	detector := &GeneratedDetector{}
+	for _, v := range defaultGeneratedPatterns {
+		detector.patterns = append(detector.patterns, regexp.MustCompile(v))
+	}
+	return detector
+}
+
+// AddPattern adds the regular expression expr of a banner line.
+func (detector *GeneratedDetector) AddPattern(expr string) error {
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return err
+	}
+	detector.patterns = append(detector.patterns, pattern)
+	return nil
+}
+
+// Detect reports whether any of lines is a banner of a generated file.
+func (detector *GeneratedDetector) Detect(lines []string) bool {
+	for _, line := range lines {
+		for _, pattern := range detector.patterns {
+			if pattern.MatchString(line) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package counter
+
+import (
+	//"fmt"
+	"testing"
+)
+
+func TestGeneratedDetector(t *testing.T) {
+	testdata := []struct {
+		lines     []string
+		generated bool
+	}{
+		{[]string{"// Code generated by protoc-gen-go. DO NOT EDIT.", "package pb"}, true},
+		{[]string{"// Copyright 2024", "", "// Code generated by MockGen. DO NOT EDIT.", "package mock"}, true},
+		{[]string{"// Code generated by hand, edit as you like.", "package a"}, false},
+		{[]string{"// do not edit the generated list by hand"}, false},
+		{[]string{"// Generated by the protocol buffer compiler.  DO NOT EDIT!", "// source: a.proto"}, true},
+		{[]string{"/*", " * @generated by thrift", " */"}, true},
+		{[]string{"# This file is autogenerated, do not edit."}, true},
+		{[]string{"/* Automatically generated by bindgen. Do not modify. */"}, true},
+		{[]string{"// the parser generated by yacc is below"}, false},
+		{[]string{"package main"}, false},
+		{[]string{"-- @generated by sqlc"}, true},
+		{[]string{"<!-- autogenerated by docgen, do not edit -->"}, true},
+		{[]string{`banner := "// Code generated by stringer. DO NOT EDIT."`}, false},
+		{[]string{`	"@generated",`}, false},
+		{[]string{`	pattern = "Generated by the protocol buffer compiler.  DO NOT EDIT!"`}, false},
+		{[]string{`print("file is auto-generated, do not edit")`}, false},
+		{[]string{"x := 1 // the @generated tag is added by gen.sh"}, false},
+	}
+
+	for i, v := range testdata {
+		generated := NewGeneratedDetector().Detect(v.lines)
+		if generated != v.generated {
+			t.Errorf("TestGeneratedDetector[%d] failed, generated = %v, wanted = %v", i, generated, v.generated)
+		}
+	}
+}
+
+func TestGeneratedDetectorAddPattern(t *testing.T) {
+	detector := NewGeneratedDetector()
+	lines := []string{"// GENERATED FILE, see gen.sh"}
+
+	if detector.Detect(lines) {
+		t.Errorf("TestGeneratedDetectorAddPattern failed, generated before AddPattern")
+	}
+
+	if err := detector.AddPattern(`^// GENERATED FILE\b`); err != nil {
+		t.Errorf("TestGeneratedDetectorAddPattern failed, err = %v", err)
+		return
+	}
+	if !detector.Detect(lines) {
+		t.Errorf("TestGeneratedDetectorAddPattern failed, not generated after AddPattern")
+	}
+
+	if err := detector.AddPattern(`(`); err == nil {
+		t.Errorf("TestGeneratedDetectorAddPattern failed, err = nil for a bad pattern")
+	}
+}
@@ -20,39 +20,60 @@ const (
 )
 
 type GoCodeCounter struct {
-	state int
+	state    int
+	comments int    // comment lines directly before current line
+	doc      int    // comment lines before current line which are its doc
+	scopes   []bool // open brackets, true for those of declarations like "var (" or "struct {"
 }
 
 func NewGoCodeCounter() *GoCodeCounter {
 	return &GoCodeCounter{}
 }
 
-func (c *GoCodeCounter) Clear() { c.state = GO_CODE_COUNT_STATE_INIT }
+func (c *GoCodeCounter) Clear() {
+	c.state = GO_CODE_COUNT_STATE_INIT
+	c.comments = 0
+	c.doc = 0
+	c.scopes = c.scopes[:0]
+}
+
+// DocLines returns how many comment lines right before the current line are
+// its doc, as it is a declaration.
+func (c *GoCodeCounter) DocLines() int {
+	return c.doc
+}
 
 func (c *GoCodeCounter) ParseLine(line string) (stat CodeStat) {
 	stat.Total = 1
+	c.doc = 0
 	line = strings.TrimSpace(line)
 
 	if len(line) == 0 {
 		switch c.state {
 		case GO_CODE_COUNT_STATE_BLOCK_COMMENT:
 			stat.Comment = 1
+			c.comments++
 		case GO_CODE_COUNT_STATE_BLOCK_STRING:
 			stat.Code = 1
+			c.comments = 0
 		default:
 			stat.Blank = 1
+			c.comments = 0
 		}
 		return stat
 	}
 
 	hasCode := false
 	hasComment := false
+	isDecl := c.state == GO_CODE_COUNT_STATE_INIT && c.isDeclaration(line)
 
 	/*if c.state != GO_CODE_COUNT_STATE_BLOCK_COMMENT && c.state != GO_CODE_COUNT_STATE_BLOCK_STRING {
 		c.state = GO_CODE_COUNT_STATE_INIT
 	}*/
 
-	for _, v := range line {
+	for i := 0; i < len(line); i++ {
+		v := line[i]
+
 		if c.state == GO_CODE_COUNT_STATE_LINE_COMMENT {
 			break
 		}
@@ -162,6 +183,17 @@ func (c *GoCodeCounter) ParseLine(line string) (stat CodeStat) {
 		case GO_CODE_COUNT_STATE_CHAR_ESCAPE:
 			c.state = GO_CODE_COUNT_STATE_CHAR
 		}
+
+		if c.state == GO_CODE_COUNT_STATE_CODE || c.state == GO_CODE_COUNT_STATE_INIT {
+			switch v {
+			case '(', '{':
+				c.scopes = append(c.scopes, c.inDeclarations() && goOpensDeclarations(line[:i], v))
+			case ')', '}':
+				if len(c.scopes) > 0 {
+					c.scopes = c.scopes[:len(c.scopes)-1]
+				}
+			}
+		}
 	}
 
 	if hasCode {
@@ -172,6 +204,17 @@ func (c *GoCodeCounter) ParseLine(line string) (stat CodeStat) {
 		stat.Comment = 1
 	}
 
+	// the comment lines directly before a declaration are its doc, they are
+	// known only here and returned by DocLines
+	if hasCode {
+		if isDecl {
+			c.doc = c.comments
+		}
+		c.comments = 0
+	} else if hasComment {
+		c.comments++
+	}
+
 	switch c.state {
 	case GO_CODE_COUNT_STATE_BLOCK_COMMENT:
 		break
@@ -185,3 +228,43 @@ func (c *GoCodeCounter) ParseLine(line string) (stat CodeStat) {
 
 	return stat
 }
+
+// inDeclarations reports whether the current line is at the top level, or in
+// a group of declarations or the body of a struct or an interface at the top
+// level, and not in a function body.
+func (c *GoCodeCounter) inDeclarations() bool {
+	return len(c.scopes) == 0 || c.scopes[len(c.scopes)-1]
+}
+
+// isDeclaration reports whether line starts a declaration, a spec of a
+// group or a field or method of a struct or an interface.
+func (c *GoCodeCounter) isDeclaration(line string) bool {
+	if !c.inDeclarations() {
+		return false
+	}
+	if len(c.scopes) > 0 {
+		return line[0] != ')' && line[0] != '}'
+	}
+
+	for _, v := range []string{"package ", "func ", "type ", "var ", "const "} {
+		if strings.HasPrefix(line, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// goOpensDeclarations reports whether bracket after code opens declarations,
+// like "var (" or "type T struct {".
+func goOpensDeclarations(code string, bracket byte) bool {
+	fields := strings.Fields(code)
+	if len(fields) == 0 {
+		return false
+	}
+
+	last := fields[len(fields)-1]
+	if bracket == '(' {
+		return last == "var" || last == "const" || last == "type"
+	}
+	return last == "struct" || last == "interface"
+}
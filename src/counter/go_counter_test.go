@@ -42,15 +42,65 @@ func TestGoCodeCounterParseLine(t *testing.T) {
 	}
 }
 
+func TestGoCodeCounterParseLines(t *testing.T) {
+	testdata := []struct {
+		lines []string
+		stat  CodeStat
+	}{
+		{[]string{"// a", "// b", "func f() {"}, CodeStat{Total: 3, Code: 1, Comment: 2, Doc: 2}},
+		{[]string{"/* a", "", "*/", "type T int"}, CodeStat{Total: 4, Code: 1, Comment: 3, Doc: 3}},
+		{[]string{"// a", "", "func f() {"}, CodeStat{Total: 3, Code: 1, Comment: 1, Blank: 1}},
+		{[]string{"x := 1 // a", "func f() {"}, CodeStat{Total: 2, Code: 2, Comment: 1}},
+		{[]string{"// a", "x := 1"}, CodeStat{Total: 2, Code: 1, Comment: 1}},
+		{[]string{"func f() {", "\t// a", "\tvar x = 1", "}"}, CodeStat{Total: 4, Code: 3, Comment: 1}},
+		{[]string{"var (", "\t// a", "\tx = 1", ")"}, CodeStat{Total: 4, Code: 3, Comment: 1, Doc: 1}},
+		{[]string{"var (", "\tx = 1", "\t// a", ")"}, CodeStat{Total: 4, Code: 3, Comment: 1}},
+		{[]string{"type T struct {", "\t// a", "\t// b", "\tX int", "}"}, CodeStat{Total: 5, Code: 3, Comment: 2, Doc: 2}},
+		{[]string{"type I interface {", "\t// a", "\tF() (int, error)", "}"}, CodeStat{Total: 4, Code: 3, Comment: 1, Doc: 1}},
+		{[]string{"func f() {", "\ttype t struct {", "\t\t// a", "\t\tx int", "\t}", "}"}, CodeStat{Total: 6, Code: 5, Comment: 1}},
+		{[]string{"func f(x interface{}) {", "\t// a", "\tconst y = 1", "}"}, CodeStat{Total: 4, Code: 3, Comment: 1}},
+		{[]string{"var s = \"{(\"", "// a", "func f() {"}, CodeStat{Total: 3, Code: 2, Comment: 1, Doc: 1}},
+	}
+
+	for i, v := range testdata {
+		counter, _ := NewCodeCounterFactory().NewCounter("go")
+
+		stat := CodeStat{}
+		for _, line := range v.lines {
+			lineStat := counter.ParseLine(line)
+			stat.Add(&lineStat)
+			stat.Doc += counter.(DocCounter).DocLines()
+		}
+
+		if stat != v.stat {
+			t.Errorf("TestGoCodeCounterParseLines[%d] failed, stat = %s, wanted = %s", i, stat.String(), v.stat.String())
+			continue
+		}
+	}
+}
+
+func TestGoCodeCounterDocLines(t *testing.T) {
+	lines := []string{"// a", "/* b */", "func f() {", "\t// c", "\treturn", "}"}
+	wanted := []int{0, 0, 2, 0, 0, 0}
+
+	counter := NewGoCodeCounter()
+	for i, line := range lines {
+		stat := counter.ParseLine(line)
+		if stat.Doc != 0 || counter.DocLines() != wanted[i] {
+			t.Errorf("TestGoCodeCounterDocLines[%d] failed, doc = %d, doc lines = %d, wanted = 0, %d", i, stat.Doc, counter.DocLines(), wanted[i])
+		}
+	}
+}
+
 func TestGoCodeCounterParseFile(t *testing.T) {
 	filename := os.Args[len(os.Args)-1] + "\\src\\testdata\\test1.go"
 
 	counter, _ := NewCodeCounterFactory().NewCounter("go")
-	wanted := CodeStat{Total: 22, Code: 13, Comment: 9, Blank: 2}
+	wanted := CodeStat{Total: 21, Code: 13, Comment: 9, Doc: 3, Blank: 1}
 
-	stat, ok := ParseFile(counter, filename)
-	if !ok {
-		t.Errorf("TestGoCodeCounterParseFile failed, ParseFile failed")
+	stat, err := ParseFile(counter, filename)
+	if err != nil {
+		t.Errorf("TestGoCodeCounterParseFile failed, ParseFile failed: %v", err)
 		return
 	}
 
@@ -0,0 +1,268 @@
+package counter
+
+import (
+	//"fmt"
+	"strings"
+)
+
+const (
+	JAVASCRIPT_CODE_COUNT_STATE_INIT            = 0
+	JAVASCRIPT_CODE_COUNT_STATE_LINE_COMMENT    = 1
+	JAVASCRIPT_CODE_COUNT_STATE_BLOCK_COMMENT   = 2
+	JAVASCRIPT_CODE_COUNT_STATE_CODE            = 3
+	JAVASCRIPT_CODE_COUNT_STATE_STRING          = 4
+	JAVASCRIPT_CODE_COUNT_STATE_STRING_ESCAPE   = 5
+	JAVASCRIPT_CODE_COUNT_STATE_TEMPLATE        = 6
+	JAVASCRIPT_CODE_COUNT_STATE_TEMPLATE_ESCAPE = 7
+	JAVASCRIPT_CODE_COUNT_STATE_REGEX           = 8
+	JAVASCRIPT_CODE_COUNT_STATE_REGEX_ESCAPE    = 9
+	JAVASCRIPT_CODE_COUNT_STATE_REGEX_CLASS     = 10
+)
+
+// keywords after which a '/' starts a regex literal rather than a division
+var javascriptRegexKeywords = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true,
+	"new": true, "delete": true, "void": true, "throw": true, "case": true,
+	"do": true, "else": true, "yield": true, "await": true,
+}
+
+type JavaScriptCodeCounter struct {
+	state     int
+	quote     byte
+	braces    int   // nesting of '{' inside current template substitution
+	templates []int // saved brace nesting of enclosing template substitutions
+	last      byte  // last significant byte of code
+	word      []byte
+	lastWord  []byte // last identifier of code
+	doc       bool   // current block comment is a jsdoc comment
+}
+
+func NewJavaScriptCodeCounter() *JavaScriptCodeCounter {
+	return &JavaScriptCodeCounter{}
+}
+
+func (c *JavaScriptCodeCounter) Clear() {
+	c.state = JAVASCRIPT_CODE_COUNT_STATE_INIT
+	c.quote = 0
+	c.braces = 0
+	c.templates = c.templates[:0]
+	c.last = 0
+	c.word = c.word[:0]
+	c.lastWord = c.lastWord[:0]
+}
+
+func (c *JavaScriptCodeCounter) ParseLine(line string) (stat CodeStat) {
+	stat.Total = 1
+	line = strings.TrimSpace(line)
+
+	if len(line) == 0 {
+		switch c.state {
+		case JAVASCRIPT_CODE_COUNT_STATE_BLOCK_COMMENT:
+			stat.Comment = 1
+			if c.doc {
+				stat.Doc = 1
+			}
+		case JAVASCRIPT_CODE_COUNT_STATE_TEMPLATE:
+			stat.Code = 1
+		default:
+			stat.Blank = 1
+		}
+		return stat
+	}
+
+	hasCode := false
+	hasComment := false
+	hasDoc := false
+
+	for i := 0; i < len(line); i++ {
+		v := line[i]
+
+		if c.state == JAVASCRIPT_CODE_COUNT_STATE_LINE_COMMENT {
+			break
+		}
+
+		switch c.state {
+		case JAVASCRIPT_CODE_COUNT_STATE_INIT, JAVASCRIPT_CODE_COUNT_STATE_CODE:
+			if javascriptIsIdent(v) {
+				c.word = append(c.word, v)
+				c.state = JAVASCRIPT_CODE_COUNT_STATE_CODE
+				c.last = v
+				hasCode = true
+				continue
+			}
+			if len(c.word) > 0 {
+				c.lastWord = append(c.lastWord[:0], c.word...)
+				c.word = c.word[:0]
+			}
+
+			switch v {
+			case ' ', '\t':
+				c.state = JAVASCRIPT_CODE_COUNT_STATE_INIT
+				continue
+			case '/':
+				switch peekByte(line, i+1) {
+				case '/':
+					c.state = JAVASCRIPT_CODE_COUNT_STATE_LINE_COMMENT
+					hasComment = true
+					continue
+				case '*':
+					c.state = JAVASCRIPT_CODE_COUNT_STATE_BLOCK_COMMENT
+					// only "/**" starts a jsdoc comment, "///" is a
+					// typescript directive
+					c.doc = peekByte(line, i+2) == '*' && isDocComment(line[i+2:], '*')
+					hasComment = true
+					hasDoc = hasDoc || c.doc
+					i++
+					continue
+				}
+				if c.isRegexStart() {
+					c.state = JAVASCRIPT_CODE_COUNT_STATE_REGEX
+				} else {
+					c.state = JAVASCRIPT_CODE_COUNT_STATE_CODE
+				}
+			case '"', '\'':
+				c.state = JAVASCRIPT_CODE_COUNT_STATE_STRING
+				c.quote = v
+			case '`':
+				c.state = JAVASCRIPT_CODE_COUNT_STATE_TEMPLATE
+			case '{':
+				c.state = JAVASCRIPT_CODE_COUNT_STATE_CODE
+				c.braces++
+			case '}':
+				c.state = JAVASCRIPT_CODE_COUNT_STATE_CODE
+				if c.braces == 0 && len(c.templates) > 0 {
+					// end of ${...}, back into the enclosing template
+					c.braces = c.templates[len(c.templates)-1]
+					c.templates = c.templates[:len(c.templates)-1]
+					c.state = JAVASCRIPT_CODE_COUNT_STATE_TEMPLATE
+				} else if c.braces > 0 {
+					c.braces--
+				}
+			default:
+				c.state = JAVASCRIPT_CODE_COUNT_STATE_CODE
+			}
+			c.last = v
+			c.lastWord = c.lastWord[:0]
+			hasCode = true
+
+		case JAVASCRIPT_CODE_COUNT_STATE_BLOCK_COMMENT:
+			hasComment = true
+			hasDoc = hasDoc || c.doc
+			if v == '*' && peekByte(line, i+1) == '/' {
+				c.state = JAVASCRIPT_CODE_COUNT_STATE_INIT
+				i++
+			}
+
+		case JAVASCRIPT_CODE_COUNT_STATE_STRING:
+			hasCode = true
+			switch v {
+			case '\\':
+				c.state = JAVASCRIPT_CODE_COUNT_STATE_STRING_ESCAPE
+			case c.quote:
+				c.state = JAVASCRIPT_CODE_COUNT_STATE_CODE
+				c.last = v
+			}
+
+		case JAVASCRIPT_CODE_COUNT_STATE_STRING_ESCAPE:
+			c.state = JAVASCRIPT_CODE_COUNT_STATE_STRING
+
+		case JAVASCRIPT_CODE_COUNT_STATE_TEMPLATE:
+			hasCode = true
+			switch v {
+			case '\\':
+				c.state = JAVASCRIPT_CODE_COUNT_STATE_TEMPLATE_ESCAPE
+			case '`':
+				c.state = JAVASCRIPT_CODE_COUNT_STATE_CODE
+				c.last = v
+			case '$':
+				if peekByte(line, i+1) == '{' {
+					c.templates = append(c.templates, c.braces)
+					c.braces = 0
+					c.state = JAVASCRIPT_CODE_COUNT_STATE_CODE
+					c.last = '{'
+					c.lastWord = c.lastWord[:0]
+					i++
+				}
+			}
+
+		case JAVASCRIPT_CODE_COUNT_STATE_TEMPLATE_ESCAPE:
+			c.state = JAVASCRIPT_CODE_COUNT_STATE_TEMPLATE
+
+		case JAVASCRIPT_CODE_COUNT_STATE_REGEX:
+			hasCode = true
+			switch v {
+			case '\\':
+				c.state = JAVASCRIPT_CODE_COUNT_STATE_REGEX_ESCAPE
+			case '[':
+				c.state = JAVASCRIPT_CODE_COUNT_STATE_REGEX_CLASS
+			case '/':
+				// a regex literal is an operand like a parenthesized expression
+				c.state = JAVASCRIPT_CODE_COUNT_STATE_CODE
+				c.last = ')'
+			}
+
+		case JAVASCRIPT_CODE_COUNT_STATE_REGEX_ESCAPE:
+			c.state = JAVASCRIPT_CODE_COUNT_STATE_REGEX
+
+		case JAVASCRIPT_CODE_COUNT_STATE_REGEX_CLASS:
+			switch v {
+			case '\\':
+				c.state = JAVASCRIPT_CODE_COUNT_STATE_REGEX_ESCAPE
+			case ']':
+				c.state = JAVASCRIPT_CODE_COUNT_STATE_REGEX
+			}
+		}
+	}
+
+	if len(c.word) > 0 {
+		c.lastWord = append(c.lastWord[:0], c.word...)
+		c.word = c.word[:0]
+	}
+
+	if hasCode {
+		stat.Code = 1
+	}
+
+	if hasComment {
+		stat.Comment = 1
+	}
+
+	if hasDoc {
+		stat.Doc = 1
+	}
+
+	switch c.state {
+	case JAVASCRIPT_CODE_COUNT_STATE_BLOCK_COMMENT:
+		break
+	case JAVASCRIPT_CODE_COUNT_STATE_TEMPLATE:
+		break
+	case JAVASCRIPT_CODE_COUNT_STATE_TEMPLATE_ESCAPE:
+		c.state = JAVASCRIPT_CODE_COUNT_STATE_TEMPLATE
+	case JAVASCRIPT_CODE_COUNT_STATE_STRING_ESCAPE:
+		// line continuation inside a string
+		c.state = JAVASCRIPT_CODE_COUNT_STATE_STRING
+	default:
+		c.state = JAVASCRIPT_CODE_COUNT_STATE_INIT
+	}
+
+	return stat
+}
+
+// isRegexStart reports whether a '/' following the last code token starts a
+// regex literal. After an operand (identifier, number, ')' or ']') it is a
+// division, anywhere else an operand is expected so it is a regex.
+func (c *JavaScriptCodeCounter) isRegexStart() bool {
+	if len(c.lastWord) > 0 {
+		return javascriptRegexKeywords[string(c.lastWord)]
+	}
+
+	switch c.last {
+	case ')', ']', '"', '\'', '`':
+		return false
+	}
+	return !javascriptIsIdent(c.last)
+}
+
+func javascriptIsIdent(v byte) bool {
+	return v == '_' || v == '$' || v >= 'a' && v <= 'z' || v >= 'A' && v <= 'Z' || v >= '0' && v <= '9' || v > 0x7f
+}
@@ -0,0 +1,62 @@
+package counter
+
+import (
+	//"fmt"
+	"os"
+	//"path/filepath"
+	"testing"
+)
+
+func TestJavaScriptCodeCounterParseLine(t *testing.T) {
+	testdata := []struct {
+		line string
+		stat CodeStat
+	}{
+		{" \t", CodeStat{Total: 1, Blank: 1}},
+		{"ab/c", CodeStat{Total: 1, Code: 1}},
+		{"ab//", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"//ab//", CodeStat{Total: 1, Comment: 1}},
+		{"ab/*tt**/ cc ", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"/** @param a */", CodeStat{Total: 1, Comment: 1, Doc: 1}},
+		{"/*! license */", CodeStat{Total: 1, Comment: 1}},
+		{"/// <reference path=\"a.d.ts\" />", CodeStat{Total: 1, Comment: 1}},
+		{"x = /\\/\\*/", CodeStat{Total: 1, Code: 1}},
+		{"x = /[/*]/g", CodeStat{Total: 1, Code: 1}},
+		{"return /\\/\\//.test(a)", CodeStat{Total: 1, Code: 1}},
+		{"x = a / b / c // c", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"x = (a) / 2 /* c */", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"x = `${a /* c */}`", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"x = `${`${b}`} // /*`", CodeStat{Total: 1, Code: 1}},
+		{"x = `${ {a: 1}.a } //`", CodeStat{Total: 1, Code: 1}},
+		{"x = '\\'//' + \"//\"", CodeStat{Total: 1, Code: 1}},
+	}
+
+	for i, v := range testdata {
+		counter, _ := NewCodeCounterFactory().NewCounter("javascript")
+
+		stat := counter.ParseLine(v.line)
+
+		if stat != v.stat {
+			t.Errorf("TestJavaScriptCodeCounterParseLine[%d] failed, stat = %s, wanted = %s", i, stat.String(), v.stat.String())
+			continue
+		}
+	}
+}
+
+func TestJavaScriptCodeCounterParseFile(t *testing.T) {
+	filename := os.Args[len(os.Args)-1] + "\\src\\testdata\\test1.js"
+
+	counter, _ := NewCodeCounterFactory().NewCounter("javascript")
+	wanted := CodeStat{Total: 17, Code: 12, Comment: 6, Blank: 1}
+
+	stat, err := ParseFile(counter, filename)
+	if err != nil {
+		t.Errorf("TestJavaScriptCodeCounterParseFile failed, ParseFile failed: %v", err)
+		return
+	}
+
+	if stat != wanted {
+		t.Errorf("TestJavaScriptCodeCounterParseFile failed, stat = %s, wanted = %s", stat.String(), wanted.String())
+		return
+	}
+}
@@ -0,0 +1,150 @@
+package counter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// line endings
+const (
+	LINE_ENDING_NONE = 0 // last line of a file
+	LINE_ENDING_LF   = 1
+	LINE_ENDING_CRLF = 2
+	LINE_ENDING_CR   = 3
+)
+
+// LineEndings counts the line endings of a file of each style.
+type LineEndings struct {
+	LF   int
+	CRLF int
+	CR   int
+}
+
+func (endings *LineEndings) Add(rhs *LineEndings) {
+	endings.LF += rhs.LF
+	endings.CRLF += rhs.CRLF
+	endings.CR += rhs.CR
+}
+
+// Mixed reports whether there are line endings of more than one style.
+func (endings *LineEndings) Mixed() bool {
+	styles := 0
+	for _, v := range []int{endings.LF, endings.CRLF, endings.CR} {
+		if v > 0 {
+			styles++
+		}
+	}
+	return styles > 1
+}
+
+// String returns the style of the line endings, like "crlf", or "mixed" with
+// the count of each style.
+func (endings *LineEndings) String() string {
+	switch {
+	case endings.Mixed():
+		return fmt.Sprintf("mixed (lf %d, crlf %d, cr %d)", endings.LF, endings.CRLF, endings.CR)
+	case endings.LF > 0:
+		return "lf"
+	case endings.CRLF > 0:
+		return "crlf"
+	case endings.CR > 0:
+		return "cr"
+	}
+	return "none"
+}
+
+// lineReader splits text into lines ending in "\n", "\r\n" or a bare "\r".
+// The text after the last line ending is a line too, unless it is empty, so
+// lines are counted as by wc -l, plus an unterminated last line.
+type lineReader struct {
+	r     io.Reader
+	buf   []byte
+	start int // buf[start:end] is read but not returned yet
+	end   int
+	err   error // error of r
+	done  bool  // the last line is returned
+}
+
+func (l *lineReader) reset(r io.Reader) {
+	l.r = r
+	l.start = 0
+	l.end = 0
+	l.err = nil
+	l.done = false
+}
+
+// next returns the next line without its ending, and its ending. The line is
+// only valid until the next call. After the last line it returns io.EOF, on
+// a read error it returns the error.
+func (l *lineReader) next() (line []byte, ending int, err error) {
+	if l.done {
+		return nil, LINE_ENDING_NONE, io.EOF
+	}
+
+	for searched := 0; ; {
+		data := l.buf[l.start:l.end]
+		if lf := bytes.IndexByte(data[searched:], '\n'); lf >= 0 {
+			lf += searched
+			if cr := bytes.IndexByte(data[searched:lf], '\r'); cr >= 0 {
+				cr += searched
+				if cr < lf-1 {
+					return l.take(cr, 1), LINE_ENDING_CR, nil
+				}
+				return l.take(cr, 2), LINE_ENDING_CRLF, nil
+			}
+			return l.take(lf, 1), LINE_ENDING_LF, nil
+		}
+
+		// without '\n' a bare '\r' ends a line, unless it is the last byte
+		// read, which may be followed by '\n'
+		if cr := bytes.IndexByte(data[searched:], '\r'); cr >= 0 && (searched+cr < len(data)-1 || l.err != nil) {
+			return l.take(searched+cr, 1), LINE_ENDING_CR, nil
+		}
+
+		if l.err != nil {
+			if l.err != io.EOF {
+				return nil, LINE_ENDING_NONE, l.err
+			}
+			l.done = true
+			if len(data) == 0 {
+				return nil, LINE_ENDING_NONE, io.EOF
+			}
+			return l.take(len(data), 0), LINE_ENDING_NONE, nil
+		}
+
+		// keep a '\r' at the end unsearched
+		searched = len(data)
+		if searched > 0 && data[searched-1] == '\r' {
+			searched--
+		}
+		l.fill()
+	}
+}
+
+// take returns the n bytes not returned yet as a line, and skips the line
+// ending of size bytes after it.
+func (l *lineReader) take(n, size int) []byte {
+	line := l.buf[l.start : l.start+n]
+	l.start += n + size
+	return line
+}
+
+// fill reads more of r into buf, moving the unreturned bytes to its start
+// and growing it for a long line.
+func (l *lineReader) fill() {
+	if l.start > 0 {
+		copy(l.buf, l.buf[l.start:l.end])
+		l.end -= l.start
+		l.start = 0
+	}
+	if l.end == len(l.buf) {
+		buf := make([]byte, 2*len(l.buf))
+		copy(buf, l.buf[:l.end])
+		l.buf = buf
+	}
+
+	n, err := l.r.Read(l.buf[l.end:])
+	l.end += n
+	l.err = err
+}
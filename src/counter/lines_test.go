@@ -0,0 +1,97 @@
+package counter
+
+import (
+	//"fmt"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestLineReader(t *testing.T) {
+	testdata := []struct {
+		content string
+		lines   []string
+		endings LineEndings
+	}{
+		{"", []string{}, LineEndings{}},
+		{"a", []string{"a"}, LineEndings{}},
+		{"a\nb\n", []string{"a", "b"}, LineEndings{LF: 2}},
+		{"a\r\nb\r\n", []string{"a", "b"}, LineEndings{CRLF: 2}},
+		{"a\rb\r", []string{"a", "b"}, LineEndings{CR: 2}},
+		{"a\r\rb\n\r\nc", []string{"a", "", "b", "", "c"}, LineEndings{LF: 1, CRLF: 1, CR: 2}},
+		{"a\n\rb", []string{"a", "", "b"}, LineEndings{LF: 1, CR: 1}},
+	}
+
+	for i, v := range testdata {
+		// read one byte at a time to split "\r\n"
+		for _, r := range []bool{false, true} {
+			reader := &lineReader{buf: make([]byte, 2)}
+			if r {
+				reader.reset(iotest.OneByteReader(strings.NewReader(v.content)))
+			} else {
+				reader.reset(strings.NewReader(v.content))
+			}
+
+			lines := []string{}
+			endings := LineEndings{}
+			for {
+				line, ending, err := reader.next()
+				if err != nil {
+					break
+				}
+				lines = append(lines, string(line))
+				switch ending {
+				case LINE_ENDING_LF:
+					endings.LF++
+				case LINE_ENDING_CRLF:
+					endings.CRLF++
+				case LINE_ENDING_CR:
+					endings.CR++
+				}
+			}
+
+			if strings.Join(lines, "|") != strings.Join(v.lines, "|") || endings != v.endings {
+				t.Errorf("TestLineReader[%d] failed, lines = %q, endings = %v, wanted = %q, %v", i, lines, endings, v.lines, v.endings)
+			}
+		}
+	}
+}
+
+func TestLineEndingsString(t *testing.T) {
+	testdata := []struct {
+		endings LineEndings
+		str     string
+		mixed   bool
+	}{
+		{LineEndings{}, "none", false},
+		{LineEndings{LF: 2}, "lf", false},
+		{LineEndings{CRLF: 2}, "crlf", false},
+		{LineEndings{CR: 2}, "cr", false},
+		{LineEndings{LF: 1, CRLF: 2}, "mixed (lf 1, crlf 2, cr 0)", true},
+	}
+
+	for i, v := range testdata {
+		if v.endings.String() != v.str || v.endings.Mixed() != v.mixed {
+			t.Errorf("TestLineEndingsString[%d] failed, str = %s, mixed = %v", i, v.endings.String(), v.endings.Mixed())
+		}
+	}
+}
+
+func TestParseReaderResult(t *testing.T) {
+	counter, _ := NewCodeCounterFactory().NewCounter("cpp")
+	wanted := FileResult{
+		Stat:        CodeStat{Total: 5, Code: 2, Comment: 2, Blank: 1},
+		LineEndings: LineEndings{LF: 1, CRLF: 1, CR: 2},
+		Bytes:       28,
+	}
+
+	result, err := ParseReaderResult(counter, strings.NewReader("int a;\r// b\r\r\n/* c */\nint d;"))
+	if err != nil {
+		t.Errorf("TestParseReaderResult failed, err = %v", err)
+		return
+	}
+
+	if result != wanted {
+		t.Errorf("TestParseReaderResult failed, result = %v, wanted = %v", result, wanted)
+	}
+}
@@ -0,0 +1,154 @@
+package counter
+
+import (
+	//"fmt"
+	"strings"
+)
+
+const (
+	PASCAL_CODE_COUNT_STATE_INIT            = 0
+	PASCAL_CODE_COUNT_STATE_LINE_COMMENT    = 1
+	PASCAL_CODE_COUNT_STATE_BRACE_COMMENT   = 2
+	PASCAL_CODE_COUNT_STATE_PAREN_COMMENT   = 3
+	PASCAL_CODE_COUNT_STATE_BRACE_DIRECTIVE = 4
+	PASCAL_CODE_COUNT_STATE_PAREN_DIRECTIVE = 5
+	PASCAL_CODE_COUNT_STATE_CODE            = 6
+	PASCAL_CODE_COUNT_STATE_STRING          = 7
+)
+
+type PascalCodeCounter struct {
+	state int
+}
+
+func NewPascalCodeCounter() *PascalCodeCounter {
+	return &PascalCodeCounter{}
+}
+
+func (c *PascalCodeCounter) Clear() { c.state = PASCAL_CODE_COUNT_STATE_INIT }
+
+func (c *PascalCodeCounter) ParseLine(line string) (stat CodeStat) {
+	stat.Total = 1
+	line = strings.TrimSpace(line)
+
+	if len(line) == 0 {
+		switch c.state {
+		case PASCAL_CODE_COUNT_STATE_BRACE_COMMENT, PASCAL_CODE_COUNT_STATE_PAREN_COMMENT:
+			stat.Comment = 1
+		case PASCAL_CODE_COUNT_STATE_BRACE_DIRECTIVE, PASCAL_CODE_COUNT_STATE_PAREN_DIRECTIVE:
+			stat.Code = 1
+		default:
+			stat.Blank = 1
+		}
+		return stat
+	}
+
+	hasCode := false
+	hasComment := false
+	hasDoc := false
+
+	for i := 0; i < len(line); i++ {
+		v := line[i]
+
+		if c.state == PASCAL_CODE_COUNT_STATE_LINE_COMMENT {
+			break
+		}
+
+		switch c.state {
+		case PASCAL_CODE_COUNT_STATE_INIT, PASCAL_CODE_COUNT_STATE_CODE:
+			switch {
+			case v == ' ' || v == '\t':
+				c.state = PASCAL_CODE_COUNT_STATE_INIT
+			case v == '/' && peekByte(line, i+1) == '/':
+				// "///" starts a delphi xml doc comment
+				c.state = PASCAL_CODE_COUNT_STATE_LINE_COMMENT
+				hasComment = true
+				hasDoc = isDocComment(line[i+2:], '/')
+			case v == '{' && peekByte(line, i+1) == '$':
+				// compiler directives are code
+				c.state = PASCAL_CODE_COUNT_STATE_BRACE_DIRECTIVE
+				hasCode = true
+				i++
+			case v == '{':
+				c.state = PASCAL_CODE_COUNT_STATE_BRACE_COMMENT
+				hasComment = true
+			case v == '(' && peekByte(line, i+1) == '*' && peekByte(line, i+2) == '$':
+				c.state = PASCAL_CODE_COUNT_STATE_PAREN_DIRECTIVE
+				hasCode = true
+				i += 2
+			case v == '(' && peekByte(line, i+1) == '*':
+				c.state = PASCAL_CODE_COUNT_STATE_PAREN_COMMENT
+				hasComment = true
+				i++
+			case v == '\'':
+				c.state = PASCAL_CODE_COUNT_STATE_STRING
+				hasCode = true
+			default:
+				c.state = PASCAL_CODE_COUNT_STATE_CODE
+				hasCode = true
+			}
+
+		case PASCAL_CODE_COUNT_STATE_BRACE_COMMENT:
+			// "(*" and "*)" have no meaning inside { }
+			hasComment = true
+			if v == '}' {
+				c.state = PASCAL_CODE_COUNT_STATE_INIT
+			}
+
+		case PASCAL_CODE_COUNT_STATE_PAREN_COMMENT:
+			// "{" and "}" have no meaning inside (* *)
+			hasComment = true
+			if v == '*' && peekByte(line, i+1) == ')' {
+				c.state = PASCAL_CODE_COUNT_STATE_INIT
+				i++
+			}
+
+		case PASCAL_CODE_COUNT_STATE_BRACE_DIRECTIVE:
+			hasCode = true
+			if v == '}' {
+				c.state = PASCAL_CODE_COUNT_STATE_CODE
+			}
+
+		case PASCAL_CODE_COUNT_STATE_PAREN_DIRECTIVE:
+			hasCode = true
+			if v == '*' && peekByte(line, i+1) == ')' {
+				c.state = PASCAL_CODE_COUNT_STATE_CODE
+				i++
+			}
+
+		case PASCAL_CODE_COUNT_STATE_STRING:
+			// '' inside a string is an escaped quote, handled as the end of
+			// one string and the start of the next
+			hasCode = true
+			if v == '\'' {
+				c.state = PASCAL_CODE_COUNT_STATE_CODE
+			}
+		}
+	}
+
+	if hasCode {
+		stat.Code = 1
+	}
+
+	if hasComment {
+		stat.Comment = 1
+	}
+
+	if hasDoc {
+		stat.Doc = 1
+	}
+
+	switch c.state {
+	case PASCAL_CODE_COUNT_STATE_BRACE_COMMENT:
+		break
+	case PASCAL_CODE_COUNT_STATE_PAREN_COMMENT:
+		break
+	case PASCAL_CODE_COUNT_STATE_BRACE_DIRECTIVE:
+		break
+	case PASCAL_CODE_COUNT_STATE_PAREN_DIRECTIVE:
+		break
+	default:
+		c.state = PASCAL_CODE_COUNT_STATE_INIT
+	}
+
+	return stat
+}
@@ -0,0 +1,60 @@
+package counter
+
+import (
+	//"fmt"
+	"os"
+	//"path/filepath"
+	"testing"
+)
+
+func TestPascalCodeCounterParseLine(t *testing.T) {
+	testdata := []struct {
+		line string
+		stat CodeStat
+	}{
+		{" \t", CodeStat{Total: 1, Blank: 1}},
+		{"ab/c", CodeStat{Total: 1, Code: 1}},
+		{"ab//", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"//ab//", CodeStat{Total: 1, Comment: 1}},
+		{"/// <summary>", CodeStat{Total: 1, Comment: 1, Doc: 1}},
+		{"{ab}", CodeStat{Total: 1, Comment: 1}},
+		{"(*ab*)", CodeStat{Total: 1, Comment: 1}},
+		{"{ (* }", CodeStat{Total: 1, Comment: 1}},
+		{"(* { *)", CodeStat{Total: 1, Comment: 1}},
+		{"{ab} cc", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"{$IFDEF DEBUG}", CodeStat{Total: 1, Code: 1}},
+		{"(*$R+*)", CodeStat{Total: 1, Code: 1}},
+		{"s := 'a''{//'", CodeStat{Total: 1, Code: 1}},
+		{"s := '' // c", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"x := (a*b)", CodeStat{Total: 1, Code: 1}},
+	}
+
+	for i, v := range testdata {
+		counter, _ := NewCodeCounterFactory().NewCounter("pascal")
+
+		stat := counter.ParseLine(v.line)
+
+		if stat != v.stat {
+			t.Errorf("TestPascalCodeCounterParseLine[%d] failed, stat = %s, wanted = %s", i, stat.String(), v.stat.String())
+			continue
+		}
+	}
+}
+
+func TestPascalCodeCounterParseFile(t *testing.T) {
+	filename := os.Args[len(os.Args)-1] + "\\src\\testdata\\test1.pas"
+
+	counter, _ := NewCodeCounterFactory().NewCounter("pascal")
+	wanted := CodeStat{Total: 13, Code: 7, Comment: 6, Blank: 1}
+
+	stat, err := ParseFile(counter, filename)
+	if err != nil {
+		t.Errorf("TestPascalCodeCounterParseFile failed, ParseFile failed: %v", err)
+		return
+	}
+
+	if stat != wanted {
+		t.Errorf("TestPascalCodeCounterParseFile failed, stat = %s, wanted = %s", stat.String(), wanted.String())
+		return
+	}
+}
@@ -0,0 +1,234 @@
+package counter
+
+import (
+	//"fmt"
+	"strings"
+)
+
+const (
+	PYTHON_CODE_COUNT_STATE_INIT                = 0
+	PYTHON_CODE_COUNT_STATE_LINE_COMMENT        = 1
+	PYTHON_CODE_COUNT_STATE_CODE                = 2
+	PYTHON_CODE_COUNT_STATE_STRING              = 3
+	PYTHON_CODE_COUNT_STATE_STRING_ESCAPE       = 4
+	PYTHON_CODE_COUNT_STATE_BLOCK_STRING        = 5
+	PYTHON_CODE_COUNT_STATE_BLOCK_STRING_ESCAPE = 6
+)
+
+type PythonCodeCounter struct {
+	state     int
+	quote     byte
+	docString bool // current string is a docstring
+	seenCode  bool // module has code, so no module docstring any more
+	inHeader  bool // current logical line starts with def or class
+	expectDoc bool // next statement may be a docstring
+	continued bool // previous line ends with '\'
+	depth     int  // nesting of (), [] and {}
+}
+
+func NewPythonCodeCounter() *PythonCodeCounter {
+	return &PythonCodeCounter{}
+}
+
+func (c *PythonCodeCounter) Clear() {
+	c.state = PYTHON_CODE_COUNT_STATE_INIT
+	c.quote = 0
+	c.docString = false
+	c.seenCode = false
+	c.inHeader = false
+	c.expectDoc = false
+	c.continued = false
+	c.depth = 0
+}
+
+func (c *PythonCodeCounter) ParseLine(line string) (stat CodeStat) {
+	stat.Total = 1
+	line = strings.TrimSpace(line)
+
+	if len(line) == 0 {
+		switch c.state {
+		case PYTHON_CODE_COUNT_STATE_BLOCK_STRING:
+			if c.docString {
+				stat.Comment = 1
+				stat.Doc = 1
+			} else {
+				stat.Code = 1
+			}
+		default:
+			stat.Blank = 1
+		}
+		return stat
+	}
+
+	hasCode := false
+	hasComment := false
+	hasDoc := false
+
+	i := 0
+
+	if c.state == PYTHON_CODE_COUNT_STATE_INIT && c.depth == 0 && !c.continued {
+		if pythonIsHeader(line) {
+			c.inHeader = true
+		}
+
+		if c.expectDoc || !c.seenCode {
+			if n := pythonStringPrefixLen(line); n >= 0 {
+				c.docString = true
+				i = n
+			}
+		}
+	}
+
+	var last byte
+
+	for ; i < len(line); i++ {
+		v := line[i]
+
+		if c.state == PYTHON_CODE_COUNT_STATE_LINE_COMMENT {
+			break
+		}
+
+		inDoc := c.docString
+
+		switch c.state {
+		case PYTHON_CODE_COUNT_STATE_INIT, PYTHON_CODE_COUNT_STATE_CODE:
+			switch v {
+			case ' ', '\t':
+				continue
+			case '#':
+				c.state = PYTHON_CODE_COUNT_STATE_LINE_COMMENT
+				hasComment = true
+				continue
+			case '"', '\'':
+				c.quote = v
+				if i+2 < len(line) && line[i+1] == v && line[i+2] == v {
+					c.state = PYTHON_CODE_COUNT_STATE_BLOCK_STRING
+					i += 2
+				} else {
+					c.state = PYTHON_CODE_COUNT_STATE_STRING
+				}
+			case '(', '[', '{':
+				c.depth++
+				c.state = PYTHON_CODE_COUNT_STATE_CODE
+			case ')', ']', '}':
+				if c.depth > 0 {
+					c.depth--
+				}
+				c.state = PYTHON_CODE_COUNT_STATE_CODE
+			default:
+				c.state = PYTHON_CODE_COUNT_STATE_CODE
+			}
+
+		case PYTHON_CODE_COUNT_STATE_STRING:
+			switch v {
+			case '\\':
+				c.state = PYTHON_CODE_COUNT_STATE_STRING_ESCAPE
+			case c.quote:
+				c.state = PYTHON_CODE_COUNT_STATE_CODE
+				c.docString = false
+			}
+
+		case PYTHON_CODE_COUNT_STATE_STRING_ESCAPE:
+			c.state = PYTHON_CODE_COUNT_STATE_STRING
+
+		case PYTHON_CODE_COUNT_STATE_BLOCK_STRING:
+			switch v {
+			case '\\':
+				c.state = PYTHON_CODE_COUNT_STATE_BLOCK_STRING_ESCAPE
+			case c.quote:
+				if i+2 < len(line) && line[i+1] == v && line[i+2] == v {
+					c.state = PYTHON_CODE_COUNT_STATE_CODE
+					c.docString = false
+					i += 2
+				}
+			}
+
+		case PYTHON_CODE_COUNT_STATE_BLOCK_STRING_ESCAPE:
+			c.state = PYTHON_CODE_COUNT_STATE_BLOCK_STRING
+		}
+
+		if inDoc {
+			hasComment = true
+			hasDoc = true
+		} else {
+			hasCode = true
+		}
+		last = v
+	}
+
+	if hasCode {
+		stat.Code = 1
+	}
+
+	if hasComment {
+		stat.Comment = 1
+	}
+
+	if hasDoc {
+		stat.Doc = 1
+	}
+
+	switch c.state {
+	case PYTHON_CODE_COUNT_STATE_BLOCK_STRING:
+		return stat
+	case PYTHON_CODE_COUNT_STATE_BLOCK_STRING_ESCAPE:
+		c.state = PYTHON_CODE_COUNT_STATE_BLOCK_STRING
+		return stat
+	case PYTHON_CODE_COUNT_STATE_STRING_ESCAPE:
+		c.state = PYTHON_CODE_COUNT_STATE_STRING
+		return stat
+	case PYTHON_CODE_COUNT_STATE_STRING:
+		// unterminated string, recover at next line
+		c.docString = false
+	}
+
+	c.state = PYTHON_CODE_COUNT_STATE_INIT
+	c.continued = last == '\\'
+
+	if hasComment && !hasCode {
+		// comment lines keep waiting for a docstring, a finished
+		// docstring ends the wait
+		if last != 0 && c.depth == 0 && !c.continued {
+			c.expectDoc = false
+			c.seenCode = true
+		}
+		return stat
+	}
+
+	if hasCode && c.depth == 0 && !c.continued {
+		c.expectDoc = c.inHeader && last == ':'
+		c.inHeader = false
+		c.seenCode = true
+	}
+
+	return stat
+}
+
+// pythonIsHeader reports whether line starts a def or class statement.
+func pythonIsHeader(line string) bool {
+	for _, v := range []string{"def ", "class ", "async def "} {
+		if strings.HasPrefix(line, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// pythonStringPrefixLen returns the length of the string prefix (r, b, u, f,
+// rb, br, fr, rf in any case) if s starts with a string literal, or -1.
+func pythonStringPrefixLen(s string) int {
+	for i := 0; i < len(s); i++ {
+		v := s[i]
+		switch v {
+		case '"', '\'':
+			return i
+		case 'r', 'R', 'b', 'B', 'u', 'U', 'f', 'F':
+			if i >= 2 {
+				return -1
+			}
+		default:
+			return -1
+		}
+	}
+	return -1
+}
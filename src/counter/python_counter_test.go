@@ -0,0 +1,56 @@
+package counter
+
+import (
+	//"fmt"
+	"os"
+	//"path/filepath"
+	"testing"
+)
+
+func TestPythonCodeCounterParseLine(t *testing.T) {
+	testdata := []struct {
+		line string
+		stat CodeStat
+	}{
+		{" \t", CodeStat{Total: 1, Blank: 1}},
+		{"\t abc \t", CodeStat{Total: 1, Code: 1}},
+		{"ab#", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"#ab#", CodeStat{Total: 1, Comment: 1}},
+		{"x = \"#\" + '#'", CodeStat{Total: 1, Code: 1}},
+		{"x = \"\\\"#\" # c", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"x = '''#'''", CodeStat{Total: 1, Code: 1}},
+		{"x = rb'\\'#'", CodeStat{Total: 1, Code: 1}},
+		{"\"\"\"docstring\"\"\"", CodeStat{Total: 1, Comment: 1, Doc: 1}},
+		{"r'docstring'", CodeStat{Total: 1, Comment: 1, Doc: 1}},
+		{"'''docstring''' # c", CodeStat{Total: 1, Comment: 1, Doc: 1}},
+	}
+
+	for i, v := range testdata {
+		counter, _ := NewCodeCounterFactory().NewCounter("python")
+
+		stat := counter.ParseLine(v.line)
+
+		if stat != v.stat {
+			t.Errorf("TestPythonCodeCounterParseLine[%d] failed, stat = %s, wanted = %s", i, stat.String(), v.stat.String())
+			continue
+		}
+	}
+}
+
+func TestPythonCodeCounterParseFile(t *testing.T) {
+	filename := os.Args[len(os.Args)-1] + "\\src\\testdata\\test1.py"
+
+	counter, _ := NewCodeCounterFactory().NewCounter("python")
+	wanted := CodeStat{Total: 29, Code: 14, Comment: 10, Doc: 7, Blank: 6}
+
+	stat, err := ParseFile(counter, filename)
+	if err != nil {
+		t.Errorf("TestPythonCodeCounterParseFile failed, ParseFile failed: %v", err)
+		return
+	}
+
+	if stat != wanted {
+		t.Errorf("TestPythonCodeCounterParseFile failed, stat = %s, wanted = %s", stat.String(), wanted.String())
+		return
+	}
+}
@@ -0,0 +1,202 @@
+package counter
+
+import (
+	//"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	RUST_CODE_COUNT_STATE_INIT          = 0
+	RUST_CODE_COUNT_STATE_LINE_COMMENT  = 1
+	RUST_CODE_COUNT_STATE_BLOCK_COMMENT = 2
+	RUST_CODE_COUNT_STATE_CODE          = 3
+	RUST_CODE_COUNT_STATE_STRING        = 4
+	RUST_CODE_COUNT_STATE_STRING_ESCAPE = 5
+	RUST_CODE_COUNT_STATE_RAW_STRING    = 6
+	RUST_CODE_COUNT_STATE_CHAR          = 7
+	RUST_CODE_COUNT_STATE_CHAR_ESCAPE   = 8
+)
+
+type RustCodeCounter struct {
+	state  int
+	depth  int  // nesting of block comments
+	hashes int  // number of '#' closing current raw string
+	doc    bool // current block comment is a documentation comment
+}
+
+func NewRustCodeCounter() *RustCodeCounter {
+	return &RustCodeCounter{}
+}
+
+func (c *RustCodeCounter) Clear() {
+	c.state = RUST_CODE_COUNT_STATE_INIT
+	c.depth = 0
+	c.hashes = 0
+}
+
+func (c *RustCodeCounter) ParseLine(line string) (stat CodeStat) {
+	stat.Total = 1
+	line = strings.TrimSpace(line)
+
+	if len(line) == 0 {
+		switch c.state {
+		case RUST_CODE_COUNT_STATE_BLOCK_COMMENT:
+			stat.Comment = 1
+			if c.doc {
+				stat.Doc = 1
+			}
+		case RUST_CODE_COUNT_STATE_STRING, RUST_CODE_COUNT_STATE_RAW_STRING:
+			stat.Code = 1
+		default:
+			stat.Blank = 1
+		}
+		return stat
+	}
+
+	hasCode := false
+	hasComment := false
+	hasDoc := false
+
+	var prev byte
+
+	for i := 0; i < len(line); i++ {
+		v := line[i]
+
+		if c.state == RUST_CODE_COUNT_STATE_LINE_COMMENT {
+			break
+		}
+
+		switch c.state {
+		case RUST_CODE_COUNT_STATE_INIT, RUST_CODE_COUNT_STATE_CODE:
+			switch {
+			case v == ' ' || v == '\t':
+				c.state = RUST_CODE_COUNT_STATE_INIT
+			case v == '/' && peekByte(line, i+1) == '/':
+				c.state = RUST_CODE_COUNT_STATE_LINE_COMMENT
+				hasComment = true
+				hasDoc = isDocComment(line[i+2:], '/')
+			case v == '/' && peekByte(line, i+1) == '*':
+				c.state = RUST_CODE_COUNT_STATE_BLOCK_COMMENT
+				c.depth = 1
+				c.doc = isDocComment(line[i+2:], '*')
+				hasComment = true
+				hasDoc = hasDoc || c.doc
+				i++
+			case v == '"':
+				c.state = RUST_CODE_COUNT_STATE_STRING
+				hasCode = true
+			case v == '\'':
+				hasCode = true
+				if peekByte(line, i+1) == '\\' {
+					c.state = RUST_CODE_COUNT_STATE_CHAR
+				} else if _, n := utf8.DecodeRuneInString(line[i+1:]); peekByte(line, i+1+n) == '\'' {
+					// 'a' is a char literal, 'a alone is a lifetime
+					c.state = RUST_CODE_COUNT_STATE_CODE
+					i += 1 + n
+				} else {
+					c.state = RUST_CODE_COUNT_STATE_CODE
+				}
+			case v == 'r' && !rustIsIdent(prev) || v == 'r' && prev == 'b' && !rustIsIdent(peekByte(line, i-2)):
+				hasCode = true
+				c.state = RUST_CODE_COUNT_STATE_CODE
+				j := i + 1
+				for j < len(line) && line[j] == '#' {
+					j++
+				}
+				if peekByte(line, j) == '"' {
+					c.state = RUST_CODE_COUNT_STATE_RAW_STRING
+					c.hashes = j - i - 1
+					i = j
+				}
+			default:
+				c.state = RUST_CODE_COUNT_STATE_CODE
+				hasCode = true
+			}
+
+		case RUST_CODE_COUNT_STATE_BLOCK_COMMENT:
+			hasComment = true
+			hasDoc = hasDoc || c.doc
+			if v == '/' && peekByte(line, i+1) == '*' {
+				c.depth++
+				i++
+			} else if v == '*' && peekByte(line, i+1) == '/' {
+				c.depth--
+				i++
+				if c.depth == 0 {
+					c.state = RUST_CODE_COUNT_STATE_INIT
+				}
+			}
+
+		case RUST_CODE_COUNT_STATE_STRING:
+			hasCode = true
+			switch v {
+			case '\\':
+				c.state = RUST_CODE_COUNT_STATE_STRING_ESCAPE
+			case '"':
+				c.state = RUST_CODE_COUNT_STATE_CODE
+			}
+
+		case RUST_CODE_COUNT_STATE_STRING_ESCAPE:
+			c.state = RUST_CODE_COUNT_STATE_STRING
+
+		case RUST_CODE_COUNT_STATE_RAW_STRING:
+			hasCode = true
+			if v == '"' {
+				j := i + 1
+				for j < len(line) && j-i-1 < c.hashes && line[j] == '#' {
+					j++
+				}
+				if j-i-1 == c.hashes {
+					c.state = RUST_CODE_COUNT_STATE_CODE
+					i = j - 1
+				}
+			}
+
+		case RUST_CODE_COUNT_STATE_CHAR:
+			hasCode = true
+			switch v {
+			case '\\':
+				c.state = RUST_CODE_COUNT_STATE_CHAR_ESCAPE
+			case '\'':
+				c.state = RUST_CODE_COUNT_STATE_CODE
+			}
+
+		case RUST_CODE_COUNT_STATE_CHAR_ESCAPE:
+			c.state = RUST_CODE_COUNT_STATE_CHAR
+		}
+
+		prev = v
+	}
+
+	if hasCode {
+		stat.Code = 1
+	}
+
+	if hasComment {
+		stat.Comment = 1
+	}
+
+	if hasDoc {
+		stat.Doc = 1
+	}
+
+	switch c.state {
+	case RUST_CODE_COUNT_STATE_BLOCK_COMMENT:
+		break
+	case RUST_CODE_COUNT_STATE_STRING:
+		break
+	case RUST_CODE_COUNT_STATE_STRING_ESCAPE:
+		c.state = RUST_CODE_COUNT_STATE_STRING
+	case RUST_CODE_COUNT_STATE_RAW_STRING:
+		break
+	default:
+		c.state = RUST_CODE_COUNT_STATE_INIT
+	}
+
+	return stat
+}
+
+func rustIsIdent(v byte) bool {
+	return v == '_' || v >= 'a' && v <= 'z' || v >= 'A' && v <= 'Z' || v >= '0' && v <= '9'
+}
@@ -0,0 +1,66 @@
+package counter
+
+import (
+	//"fmt"
+	"os"
+	//"path/filepath"
+	"testing"
+)
+
+func TestRustCodeCounterParseLine(t *testing.T) {
+	testdata := []struct {
+		line string
+		stat CodeStat
+	}{
+		{" \t", CodeStat{Total: 1, Blank: 1}},
+		{"ab/c", CodeStat{Total: 1, Code: 1}},
+		{"ab//", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"/// doc", CodeStat{Total: 1, Comment: 1, Doc: 1}},
+		{"//! doc", CodeStat{Total: 1, Comment: 1, Doc: 1}},
+		{"//// banner", CodeStat{Total: 1, Comment: 1}},
+		{"/** doc */ x", CodeStat{Total: 1, Code: 1, Comment: 1, Doc: 1}},
+		{"/*! doc */", CodeStat{Total: 1, Comment: 1, Doc: 1}},
+		{"/**/ x", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"/*** banner */", CodeStat{Total: 1, Comment: 1}},
+		{"/* a /* b */ c */", CodeStat{Total: 1, Comment: 1}},
+		{"/* a /* b */ c */ x", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"/* a /* b */ x", CodeStat{Total: 1, Comment: 1}},
+		{"let c = '\"'; //", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"let c = '\\''; /*", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"fn f<'a>(x: &'a str) // c", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"r#\"\"//\"#", CodeStat{Total: 1, Code: 1}},
+		{"r##\"\"#//\"##", CodeStat{Total: 1, Code: 1}},
+		{"br\"\\\" //", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"b\"\\\"//\"", CodeStat{Total: 1, Code: 1}},
+		{"r#match // c", CodeStat{Total: 1, Code: 1, Comment: 1}},
+	}
+
+	for i, v := range testdata {
+		counter, _ := NewCodeCounterFactory().NewCounter("rust")
+
+		stat := counter.ParseLine(v.line)
+
+		if stat != v.stat {
+			t.Errorf("TestRustCodeCounterParseLine[%d] failed, stat = %s, wanted = %s", i, stat.String(), v.stat.String())
+			continue
+		}
+	}
+}
+
+func TestRustCodeCounterParseFile(t *testing.T) {
+	filename := os.Args[len(os.Args)-1] + "\\src\\testdata\\test1.rs"
+
+	counter, _ := NewCodeCounterFactory().NewCounter("rust")
+	wanted := CodeStat{Total: 19, Code: 13, Comment: 5, Doc: 2, Blank: 2}
+
+	stat, err := ParseFile(counter, filename)
+	if err != nil {
+		t.Errorf("TestRustCodeCounterParseFile failed, ParseFile failed: %v", err)
+		return
+	}
+
+	if stat != wanted {
+		t.Errorf("TestRustCodeCounterParseFile failed, stat = %s, wanted = %s", stat.String(), wanted.String())
+		return
+	}
+}
@@ -0,0 +1,349 @@
+package counter
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	TABLE_CODE_COUNT_STATE_INIT          = 0
+	TABLE_CODE_COUNT_STATE_LINE_COMMENT  = 1
+	TABLE_CODE_COUNT_STATE_BLOCK_COMMENT = 2
+	TABLE_CODE_COUNT_STATE_CODE          = 3
+	TABLE_CODE_COUNT_STATE_STRING        = 4
+)
+
+// BlockCommentDefinition is a block comment like /* ... */.
+type BlockCommentDefinition struct {
+	Start  string `json:"start"`
+	End    string `json:"end"`
+	Nested bool   `json:"nested"` // may contain other block comments
+	Doc    bool   `json:"doc"`    // is a documentation comment
+}
+
+// StringDefinition is a string literal. Escape is the escape prefix, like
+// "\\", or empty for raw strings. An Escape equal to End means the doubled
+// End is an escaped End, like the doubled quote in SQL strings.
+type StringDefinition struct {
+	Start     string `json:"start"`
+	End       string `json:"end"`
+	Escape    string `json:"escape"`
+	Multiline bool   `json:"multiline"`
+}
+
+// LanguageDefinition describes the lexical syntax of a language, which is
+// enough for TableCodeCounter to count its code.
+type LanguageDefinition struct {
+	Name            string                    `json:"name"`
+	Extensions      []string                  `json:"extensions"`
+	Filenames       []string                  `json:"filenames"`
+	LineComments    []string                  `json:"line_comments"`
+	DocLineComments []string                  `json:"doc_line_comments"`
+	BlockComments   []*BlockCommentDefinition `json:"block_comments"`
+	Strings         []*StringDefinition       `json:"strings"`
+	Default         *bool                     `json:"default"` // counted without a filter, true if not set
+}
+
+// CountedByDefault reports whether the files of the language are counted
+// without a filter. Data and markup languages like yaml and html are not.
+func (def *LanguageDefinition) CountedByDefault() bool {
+	return def.Default == nil || *def.Default
+}
+
+// Filters returns the file filters of the language, like "*.rb;Rakefile".
+func (def *LanguageDefinition) Filters() string {
+	filters := []string{}
+	for _, v := range def.Extensions {
+		filters = append(filters, "*."+strings.TrimPrefix(v, "."))
+	}
+	filters = append(filters, def.Filenames...)
+	return strings.Join(filters, ";")
+}
+
+func (def *LanguageDefinition) check() error {
+	if def.Name == "" {
+		return fmt.Errorf("language definition without name")
+	}
+	for _, v := range def.BlockComments {
+		if v.Start == "" || v.End == "" {
+			return fmt.Errorf("language %s: block comment without start or end", def.Name)
+		}
+	}
+	for _, v := range def.Strings {
+		if v.Start == "" || v.End == "" {
+			return fmt.Errorf("language %s: string without start or end", def.Name)
+		}
+	}
+	return nil
+}
+
+// LoadLanguageDefinitions reads a json array of language definitions.
+func LoadLanguageDefinitions(r io.Reader) (defs []*LanguageDefinition, err error) {
+	if err = json.NewDecoder(r).Decode(&defs); err != nil {
+		return nil, err
+	}
+
+	for _, v := range defs {
+		if err = v.check(); err != nil {
+			return nil, err
+		}
+	}
+	return defs, nil
+}
+
+// LoadLanguageFile reads a json file of language definitions.
+func LoadLanguageFile(filename string) (defs []*LanguageDefinition, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defs, err = LoadLanguageDefinitions(file)
+	file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return defs, nil
+}
+
+//go:embed languages.json
+var builtinLanguages string
+
+// BuiltinLanguageDefinitions returns the language definitions shipped with
+// the counter package.
+func BuiltinLanguageDefinitions() []*LanguageDefinition {
+	defs, err := LoadLanguageDefinitions(strings.NewReader(builtinLanguages))
+	if err != nil {
+		panic("counter: invalid builtin languages.json: " + err.Error())
+	}
+	return defs
+}
+
+// TableCodeCounter counts code of any language given by a LanguageDefinition.
+type TableCodeCounter struct {
+	def   *LanguageDefinition
+	state int
+	block *BlockCommentDefinition // current block comment
+	depth int                     // nesting of current block comment
+	str   *StringDefinition       // current string
+}
+
+func NewTableCodeCounter(def *LanguageDefinition) *TableCodeCounter {
+	// longest delimiters first, so "/**" is found before "/*"
+	sorted := *def
+	sorted.LineComments = sortByLength(def.LineComments)
+	sorted.DocLineComments = sortByLength(def.DocLineComments)
+	sorted.BlockComments = append([]*BlockCommentDefinition{}, def.BlockComments...)
+	sort.SliceStable(sorted.BlockComments, func(i, j int) bool {
+		return len(sorted.BlockComments[i].Start) > len(sorted.BlockComments[j].Start)
+	})
+	sorted.Strings = append([]*StringDefinition{}, def.Strings...)
+	sort.SliceStable(sorted.Strings, func(i, j int) bool {
+		return len(sorted.Strings[i].Start) > len(sorted.Strings[j].Start)
+	})
+
+	return &TableCodeCounter{def: &sorted}
+}
+
+func (c *TableCodeCounter) Clear() {
+	c.state = TABLE_CODE_COUNT_STATE_INIT
+	c.block = nil
+	c.depth = 0
+	c.str = nil
+}
+
+func (c *TableCodeCounter) ParseLine(line string) (stat CodeStat) {
+	stat.Total = 1
+	line = strings.TrimSpace(line)
+
+	if len(line) == 0 {
+		switch c.state {
+		case TABLE_CODE_COUNT_STATE_BLOCK_COMMENT:
+			stat.Comment = 1
+			if c.block.Doc {
+				stat.Doc = 1
+			}
+		case TABLE_CODE_COUNT_STATE_STRING:
+			stat.Code = 1
+		default:
+			stat.Blank = 1
+		}
+		return stat
+	}
+
+	hasCode := false
+	hasComment := false
+	hasDoc := false
+
+	for i := 0; i < len(line); {
+		rest := line[i:]
+
+		if c.state == TABLE_CODE_COUNT_STATE_LINE_COMMENT {
+			break
+		}
+
+		switch c.state {
+		case TABLE_CODE_COUNT_STATE_INIT, TABLE_CODE_COUNT_STATE_CODE:
+			// the longest comment start wins, like "--[[" over "--" in lua
+			doc := hasPrefixIn(rest, c.def.DocLineComments)
+			lineComment := hasPrefixIn(rest, c.def.LineComments)
+			block := c.findBlockComment(rest)
+			if block != nil && (len(block.Start) <= len(doc) || len(block.Start) <= len(lineComment)) {
+				block = nil
+			}
+			if doc != "" && len(doc) >= len(lineComment) && block == nil {
+				c.state = TABLE_CODE_COUNT_STATE_LINE_COMMENT
+				hasComment = true
+				hasDoc = true
+				continue
+			}
+			if lineComment != "" && block == nil {
+				c.state = TABLE_CODE_COUNT_STATE_LINE_COMMENT
+				hasComment = true
+				continue
+			}
+			if block != nil {
+				c.state = TABLE_CODE_COUNT_STATE_BLOCK_COMMENT
+				c.block = block
+				c.depth = 1
+				hasComment = true
+				hasDoc = hasDoc || block.Doc
+				i += len(block.Start)
+				continue
+			}
+			if str := c.findString(rest); str != nil {
+				c.state = TABLE_CODE_COUNT_STATE_STRING
+				c.str = str
+				hasCode = true
+				i += len(str.Start)
+				continue
+			}
+			if line[i] == ' ' || line[i] == '\t' {
+				c.state = TABLE_CODE_COUNT_STATE_INIT
+			} else {
+				c.state = TABLE_CODE_COUNT_STATE_CODE
+				hasCode = true
+			}
+			i++
+
+		case TABLE_CODE_COUNT_STATE_BLOCK_COMMENT:
+			hasComment = true
+			hasDoc = hasDoc || c.block.Doc
+			if strings.HasPrefix(rest, c.block.End) {
+				i += len(c.block.End)
+				c.depth--
+				if c.depth == 0 || !c.block.Nested {
+					c.state = TABLE_CODE_COUNT_STATE_INIT
+				}
+				continue
+			}
+			// any start closed by the same end nests, like "/*" in "/** */"
+			if block := c.findBlockComment(rest); c.block.Nested && block != nil && block.End == c.block.End {
+				i += len(block.Start)
+				c.depth++
+				continue
+			}
+			i++
+
+		case TABLE_CODE_COUNT_STATE_STRING:
+			hasCode = true
+			escape := c.str.Escape
+			switch {
+			case escape != "" && escape == c.str.End && strings.HasPrefix(rest, escape+escape):
+				i += 2 * len(escape)
+			case escape != "" && escape != c.str.End && strings.HasPrefix(rest, escape):
+				i += len(escape) + 1
+			case strings.HasPrefix(rest, c.str.End):
+				i += len(c.str.End)
+				c.state = TABLE_CODE_COUNT_STATE_CODE
+			default:
+				i++
+			}
+		}
+	}
+
+	if hasCode {
+		stat.Code = 1
+	}
+
+	if hasComment {
+		stat.Comment = 1
+	}
+
+	if hasDoc {
+		stat.Doc = 1
+	}
+
+	switch c.state {
+	case TABLE_CODE_COUNT_STATE_BLOCK_COMMENT:
+		break
+	case TABLE_CODE_COUNT_STATE_STRING:
+		if !c.str.Multiline {
+			c.state = TABLE_CODE_COUNT_STATE_INIT
+		}
+	default:
+		c.state = TABLE_CODE_COUNT_STATE_INIT
+	}
+
+	return stat
+}
+
+// findBlockComment returns the block comment which s starts with. A start
+// overlapped by its end, like "/**" in "/**/", is only taken if no other
+// start is found, so "/**/" is "/*" followed by its end.
+func (c *TableCodeCounter) findBlockComment(s string) *BlockCommentDefinition {
+	var overlapped *BlockCommentDefinition
+	for _, v := range c.def.BlockComments {
+		if !strings.HasPrefix(s, v.Start) {
+			continue
+		}
+		if v.overlapped(s) {
+			if overlapped == nil {
+				overlapped = v
+			}
+			continue
+		}
+		return v
+	}
+	return overlapped
+}
+
+// overlapped reports whether the end of the block comment starts inside its
+// start at the beginning of s.
+func (def *BlockCommentDefinition) overlapped(s string) bool {
+	for i := 1; i < len(def.Start) && i < len(s); i++ {
+		if strings.HasPrefix(s[i:], def.End) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *TableCodeCounter) findString(s string) *StringDefinition {
+	for _, v := range c.def.Strings {
+		if strings.HasPrefix(s, v.Start) {
+			return v
+		}
+	}
+	return nil
+}
+
+// hasPrefixIn returns the first of prefixes which s starts with, or "".
+func hasPrefixIn(s string, prefixes []string) string {
+	for _, v := range prefixes {
+		if v != "" && strings.HasPrefix(s, v) {
+			return v
+		}
+	}
+	return ""
+}
+
+func sortByLength(s []string) []string {
+	sorted := append([]string{}, s...)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	return sorted
+}
@@ -0,0 +1,164 @@
+package counter
+
+import (
+	//"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTableCodeCounterParseLine(t *testing.T) {
+	def := &LanguageDefinition{
+		Name:            "test",
+		LineComments:    []string{"--", "#"},
+		DocLineComments: []string{"---"},
+		BlockComments: []*BlockCommentDefinition{
+			{Start: "{-", End: "-}", Nested: true},
+			{Start: "--[[", End: "]]"},
+			{Start: "/**", End: "*/", Doc: true},
+		},
+		Strings: []*StringDefinition{
+			{Start: "\"", End: "\"", Escape: "\\"},
+			{Start: "'", End: "'", Escape: "'"},
+			{Start: "r\"", End: "\""},
+		},
+	}
+
+	testdata := []struct {
+		line string
+		stat CodeStat
+	}{
+		{" \t", CodeStat{Total: 1, Blank: 1}},
+		{"ab-c", CodeStat{Total: 1, Code: 1}},
+		{"ab--", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"# ab", CodeStat{Total: 1, Comment: 1}},
+		{"--- ab", CodeStat{Total: 1, Comment: 1, Doc: 1}},
+		{"--[[ ab ]] cc", CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"{- {- -} ab -}", CodeStat{Total: 1, Comment: 1}},
+		{"/** ab */", CodeStat{Total: 1, Comment: 1, Doc: 1}},
+		{"s = \"\\\"--\"", CodeStat{Total: 1, Code: 1}},
+		{"s = 'it''s --'", CodeStat{Total: 1, Code: 1}},
+		{"s = r\"\\\" -- c", CodeStat{Total: 1, Code: 1, Comment: 1}},
+	}
+
+	for i, v := range testdata {
+		counter := NewTableCodeCounter(def)
+
+		stat := counter.ParseLine(v.line)
+
+		if stat != v.stat {
+			t.Errorf("TestTableCodeCounterParseLine[%d] failed, stat = %s, wanted = %s", i, stat.String(), v.stat.String())
+			continue
+		}
+	}
+}
+
+func TestTableCodeCounterParseLines(t *testing.T) {
+	testdata := []struct {
+		name  string
+		lines []string
+		stat  CodeStat
+	}{
+		{"lua", []string{"--[[", "", "ab", "]] x = [[", "", "--]]"}, CodeStat{Total: 6, Code: 3, Comment: 4}},
+		{"haskell", []string{"{- a", "{- b -}", "-}", "main = 1"}, CodeStat{Total: 4, Code: 1, Comment: 3}},
+		{"ruby", []string{"=begin", "a", "=end", "x = 1"}, CodeStat{Total: 4, Code: 1, Comment: 3}},
+		{"sql", []string{"select 'a''--' -- c", "/* b", "*/"}, CodeStat{Total: 3, Code: 1, Comment: 3}},
+		// "/**/" is an empty "/*" comment, not a doc comment left open
+		{"kotlin", []string{"/**/", "val x = 1", "val y = 2"}, CodeStat{Total: 3, Code: 2, Comment: 1}},
+		{"swift", []string{"/***/", "let x = 1"}, CodeStat{Total: 2, Code: 1, Comment: 1, Doc: 1}},
+		{"php", []string{"/**/ $x = 1;", "/** a */"}, CodeStat{Total: 2, Code: 1, Comment: 2, Doc: 1}},
+		// starts of the same end nest in each other
+		{"kotlin", []string{"/** a /* b */ c */", "val x = 1"}, CodeStat{Total: 2, Code: 1, Comment: 1, Doc: 1}},
+		{"kotlin", []string{"/* a /**/ b */ val x = 1"}, CodeStat{Total: 1, Code: 1, Comment: 1}},
+		{"swift", []string{"/* a", "/** b */", "*/ let x = 1"}, CodeStat{Total: 3, Code: 1, Comment: 3}},
+		{"haskell", []string{"{-| a", "{- b -}", "-}", "main = 1"}, CodeStat{Total: 4, Code: 1, Comment: 3, Doc: 3}},
+		{"haskell", []string{"{- a {-| b -} c -} main = 1"}, CodeStat{Total: 1, Code: 1, Comment: 1}},
+	}
+
+	for i, v := range testdata {
+		counter, ok := NewCodeCounterFactory().NewCounter(v.name)
+		if !ok {
+			t.Errorf("TestTableCodeCounterParseLines[%d] failed, no counter for %s", i, v.name)
+			continue
+		}
+
+		stat := CodeStat{}
+		for _, line := range v.lines {
+			lineStat := counter.ParseLine(line)
+			stat.Add(&lineStat)
+		}
+
+		if stat != v.stat {
+			t.Errorf("TestTableCodeCounterParseLines[%d] failed, stat = %s, wanted = %s", i, stat.String(), v.stat.String())
+			continue
+		}
+	}
+}
+
+func TestLoadLanguageDefinitions(t *testing.T) {
+	testdata := []struct {
+		json    string
+		filters []string
+		ok      bool
+	}{
+		{`[{"name": "a", "extensions": ["a", ".b"], "filenames": ["Afile"]}]`, []string{"*.a;*.b;Afile"}, true},
+		{`[{"name": "a"}, {"name": "b", "extensions": ["b"]}]`, []string{"", "*.b"}, true},
+		{`[{"extensions": ["a"]}]`, nil, false},
+		{`[{"name": "a", "block_comments": [{"start": "/*"}]}]`, nil, false},
+		{`[{"name": "a", "strings": [{"end": "'"}]}]`, nil, false},
+		{`{"name": "a"}`, nil, false},
+	}
+
+	for i, v := range testdata {
+		defs, err := LoadLanguageDefinitions(strings.NewReader(v.json))
+
+		if (err == nil) != v.ok {
+			t.Errorf("TestLoadLanguageDefinitions[%d] failed, err = %v", i, err)
+			continue
+		}
+
+		if len(defs) != len(v.filters) {
+			t.Errorf("TestLoadLanguageDefinitions[%d] failed, len(defs) = %d, wanted = %d", i, len(defs), len(v.filters))
+			continue
+		}
+
+		for j, def := range defs {
+			if def.Filters() != v.filters[j] {
+				t.Errorf("TestLoadLanguageDefinitions[%d] failed, filters = %s, wanted = %s", i, def.Filters(), v.filters[j])
+			}
+		}
+	}
+}
+
+func TestBuiltinLanguageDefinitions(t *testing.T) {
+	factory := NewCodeCounterFactory()
+
+	for _, v := range BuiltinLanguageDefinitions() {
+		if _, ok := factory.NewCounter(v.Name); !ok {
+			t.Errorf("TestBuiltinLanguageDefinitions failed, no counter for %s", v.Name)
+		}
+	}
+}
+
+func TestLanguageDefinitionCountedByDefault(t *testing.T) {
+	defs, err := LoadLanguageDefinitions(strings.NewReader(`[{"name": "a"}, {"name": "b", "default": true}, {"name": "c", "default": false}]`))
+	if err != nil {
+		t.Errorf("TestLanguageDefinitionCountedByDefault failed, err = %v", err)
+		return
+	}
+
+	wanted := []bool{true, true, false}
+	for i, def := range defs {
+		if def.CountedByDefault() != wanted[i] {
+			t.Errorf("TestLanguageDefinitionCountedByDefault[%d] failed, counted = %v, wanted = %v", i, def.CountedByDefault(), wanted[i])
+		}
+	}
+
+	for _, def := range BuiltinLanguageDefinitions() {
+		switch def.Name {
+		case "html", "css", "yaml", "toml":
+			if def.CountedByDefault() {
+				t.Errorf("TestLanguageDefinitionCountedByDefault failed, %s is counted by default", def.Name)
+			}
+		}
+	}
+}
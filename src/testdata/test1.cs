@@ -0,0 +1,18 @@
+/// <summary>
+/// xml doc
+/// </summary>
+class Foo
+{
+    // comment1
+    string a = @"c:\temp\";
+    string b = @"multi
+/* not a comment */
+""quoted""";
+    string c = $"{a} // {{not}} {(b == "x" ? "/*" : "y")}";
+
+    string d = """
+        raw " "" /* text
+        """;
+    string e = $$"""{{a}} {x} //""";
+    char f = '\''; /* comment2 */
+}
\ No newline at end of file
@@ -0,0 +1,17 @@
+// comment1
+import x from "./x.js";
+
+/*
+ * block
+ */
+const re = /\/\*/g;
+const half = total / 2 / count; // comment2
+const cls = /[/*]/.test(s);
+const msg = `line1 /* not a comment
+${ items.map(i => `nested ${ i /* c */ } //`) }
+// still template
+
+end`;
+function f(a) {
+  return /^\/\//.test(a) ? '/*' : a;
+}
\ No newline at end of file
@@ -0,0 +1,13 @@
+program Test;
+{$APPTYPE CONSOLE}
+{ brace comment (* not nested *)
+  still comment }
+(* paren comment { not nested }
+*)
+
+// line comment
+var
+  s: string = 'it''s { not } a (* comment *)';
+begin
+  (*$I+*) WriteLn(s); { comment }
+end.
\ No newline at end of file
@@ -0,0 +1,29 @@
+#!/usr/bin/env python
+"""module docstring
+
+spans lines
+"""
+
+import os
+
+
+class Foo(object):
+    '''class docstring'''
+
+    def bar(self, x,
+            y):
+        # comment1
+        r"""raw docstring \""" still doc
+        """
+        s = "#" + '"'  # comment2
+        t = """
+# not a comment
+"""
+        u = rb'\'' + f"{x}"
+        return s
+
+
+def baz():
+    x = 1
+    "not a docstring"
+    return x
\ No newline at end of file
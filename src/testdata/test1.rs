@@ -0,0 +1,19 @@
+//! crate doc
+use std::fmt;
+
+/* outer /* inner */
+   still comment */
+/// doc comment
+fn longest<'a>(x: &'a str, y: &'a str) -> &'a str {
+    let c = '"'; // comment
+    let q = '\'';
+    let s = "/* not
+a comment";
+    let r = r#"raw " /* "#;
+    let h = r##"
+"# still raw
+"##;
+    let b = br"\" + b"\"//";
+
+    x
+}
\ No newline at end of file
@@ -0,0 +1,15 @@
+#include <string>
+
+// comment1 \
+   spliced comment
+const char* a = R"(raw " \ /* not a comment)";
+const char* b = R"xy(
+)" // still raw
+)x"
+)xy"; // comment2
+auto c = u8R"(
+)"; auto d = LR"-()-";
+
+const char* e = "string \
+// spliced string";
+int f = 0; // comment3
\ No newline at end of file
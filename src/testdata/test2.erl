@@ -0,0 +1,18 @@
+-module(test2).
+
+start() ->
+    X = $%, % comment1
+    Y = $", Z = $\\,
+    A = 'it\'s % atom',
+    S = "say \"%\"",
+    T = """
+        % not a comment
+
+        "still" string
+        """,
+    U = ~"sigil % string",
+    V = ~S(no \) % escape), % comment2
+    W = ~b"""
+      % text
+      """,
+    {X, Y, Z, A, S, T, U, V, W}.
\ No newline at end of file